@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	"blackjack/pkg/blackjack"
+)
+
+// coach gives basic strategy hints and keeps track of the decisions that
+// went against basic strategy.
+type coach struct {
+	strategy  *blackjack.Strategy
+	decisions int
+	mistakes  int
+	// cost is the expected value given up by the mistakes, in dollars.
+	cost float64
+}
+
+func newCoach(rules blackjack.Rules) *coach {
+	return &coach{strategy: blackjack.NewStrategy(rules)}
+}
+
+// hint prints the basic strategy action for the decision in game.
+func (c *coach) hint(game *blackjack.Game) {
+	best, evs := c.strategy.Best(game)
+	fmt.Printf("Basic strategy: %v (expected value per $1 bet: %s)\n", best, blackjack.FormatEVs(evs))
+}
+
+// decide returns a function that records the action taken in game, to
+// be called once it has been done. The strategy is worked out before the
+// action changes the game.
+func (c *coach) decide(game *blackjack.Game) func(blackjack.Action) {
+	best, evs := c.strategy.Best(game)
+	bet := game.Hand().Bet
+	return func(action blackjack.Action) {
+		c.decisions++
+		if action != best && evs[action] < evs[best] {
+			c.mistakes++
+			c.cost += (evs[best] - evs[action]) * float64(bet)
+		}
+	}
+}
+
+func (c *coach) report() {
+	fmt.Printf("Basic strategy: %d of %d decisions went against it, costing about $%.2f in expected value.\n",
+		c.mistakes, c.decisions, c.cost)
+}
@@ -2,181 +2,342 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"math/rand"
 	"os"
 	"strconv"
 	"strings"
 	"time"
-)
-
-type Card struct {
-	Suit  string
-	Value string
-}
 
-type Deck []Card
-
-var suits = []string{"Hearts", "Diamonds", "Clubs", "Spades"}
-var values = []string{"2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K", "A"}
-
-type Hand []Card
+	"blackjack/pkg/blackjack"
+	"blackjack/pkg/history"
+)
 
-func newDeck() Deck {
-	var deck Deck
-	for _, suit := range suits {
-		for _, value := range values {
-			deck = append(deck, Card{Suit: suit, Value: value})
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "sim":
+			runSim(os.Args[2:])
+			return
+		case "train":
+			runTrain(os.Args[2:])
+			return
+		case "stats":
+			runStats(os.Args[2:])
+			return
 		}
 	}
-	return deck
-}
-
-func (deck Deck) shuffle() Deck {
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(deck), func(i, j int) {
-		deck[i], deck[j] = deck[j], deck[i]
-	})
-	return deck
-}
 
-func (deck *Deck) drawCard() Card {
-	card := (*deck)[0]
-	*deck = (*deck)[1:]
-	return card
-}
+	rules, rulesFile := ruleFlags(flag.CommandLine)
+	hints := flag.Bool("hints", false, "offer basic strategy hints and report mistakes at the end")
+	chart := flag.Bool("chart", false, "print the basic strategy chart for the rules and exit")
+	seatList := flag.String("seats", human, fmt.Sprintf("comma separated seats in dealing order, up to %d: %v or a bot strategy (%v)",
+		blackjack.MaxSeats, human, strings.Join(blackjack.Players, ", ")))
+	botBet := flag.Int("bot-bet", 10, "base bet of the bots")
+	spread := flag.Int("spread", 8, "most base bets a count bot bets")
+	names := flag.String("names", "player", "comma separated profile names of the human seats, in seat order")
+	profiles := flag.String("profiles", history.DefaultFile(), "file keeping the human players' bankrolls and hands; empty to keep nothing")
+	flag.Parse()
 
-func (h Hand) calculateValue() int {
-	totalValue := 0
-	aces := 0
-
-	for _, card := range h {
-		switch card.Value {
-		case "A":
-			aces++
-			totalValue += 11
-		case "K", "Q", "J":
-			totalValue += 10
-		default:
-			val, _ := strconv.Atoi(card.Value)
-			totalValue += val
-		}
+	if err := loadRules(flag.CommandLine, rules, *rulesFile); err != nil {
+		fmt.Println("Error loading rules:", err)
+		os.Exit(1)
 	}
 
-	for aces > 0 && totalValue > 21 {
-		totalValue -= 10
-		aces--
+	if *chart {
+		fmt.Println("Basic strategy for", rules)
+		fmt.Println()
+		fmt.Print(blackjack.NewStrategy(*rules).Chart())
+		return
 	}
-
-	return totalValue
-}
-
-func (h Hand) String() string {
-	var cards []string
-	for _, card := range h {
-		cards = append(cards, fmt.Sprintf("%s of %s", card.Value, card.Suit))
+	seats, err := parseSeats(*seatList, *rules, *spread)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	book := &history.Book{}
+	if *profiles != "" {
+		if book, err = history.Load(*profiles); err != nil {
+			fmt.Println("Error loading profiles:", err)
+			os.Exit(1)
+		}
+		if err := openProfiles(seats, book, strings.Split(*names, ",")); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+	var advisor *coach
+	if *hints {
+		advisor = newCoach(*rules)
 	}
-	return strings.Join(cards, ", ")
-}
 
-func main() {
 	reader := bufio.NewReader(os.Stdin)
 
-	deck := newDeck().shuffle()
-	playerHand := Hand{}
-	dealerHand := Hand{}
-	balance := 1000
-	bet := 0
+	shoe := blackjack.NewShoe(rules.Decks, rules.Penetration, rand.New(rand.NewSource(time.Now().UnixNano())))
+	balances := make([]int, len(seats))
+	for i, s := range seats {
+		balances[i] = history.Stake
+		if s.profile != nil {
+			balances[i] = s.profile.Balance
+		}
+	}
+	game := blackjack.NewGame(*rules, shoe, balances...)
+	t := &table{game: game, seats: seats}
 
 	fmt.Println("Welcome to Blackjack!")
-	for {
-		fmt.Printf("Current balance: $%d\n", balance)
-		fmt.Print("Enter your bet (q to quit): ")
+	fmt.Println("Table rules:", rules)
+	for t.humans() {
+		// shuffle before betting so counting bots bet on a fresh count
+		if shoe.ShuffleIfDue() {
+			fmt.Println("The cut card is out. Shuffling the shoe.")
+			t.tell([]blackjack.Event{{Kind: blackjack.Shuffled}})
+		}
 
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(input)
-		if input == "q" {
+		bets := make([]int, len(seats))
+		for i, s := range seats {
+			balance := game.Seats[i].Balance
+			switch {
+			case s.left:
+			case s.bot != nil:
+				bets[i] = min(s.bot.Bet(*botBet, shoe.DecksLeft()), balance)
+				if bets[i] > 0 {
+					fmt.Printf("%sBets $%d.\n", t.seatLabel(i), bets[i])
+				}
+			default:
+				bets[i] = askBet(reader, t.seatLabel(i), balance)
+				s.left = bets[i] == 0
+			}
+		}
+		if !t.humans() {
 			break
 		}
-
-		var err error
-		bet, err = strconv.Atoi(input)
-		if err != nil || bet <= 0 || bet > balance {
+		if err := game.Deal(bets...); err != nil {
 			fmt.Println("Invalid bet amount. Try again.")
 			continue
 		}
+		fmt.Println("Dealer's hand:", game.Upcard(), ", [HIDDEN]")
+		for i, s := range game.Seats {
+			if s.Playing() {
+				fmt.Println(t.seatLabel(i)+"Your hand:", s.Hands[0].Cards)
+			}
+		}
+		t.show(game.Events())
 
-		balance -= bet
-
-		playerHand = Hand{deck.drawCard(), deck.drawCard()}
-		dealerHand = Hand{deck.drawCard(), deck.drawCard()}
-
-		fmt.Println("Dealer's hand:", dealerHand[0], ", [HIDDEN]")
-		fmt.Println("Your hand:", playerHand)
+		for game.Phase() == blackjack.PlayerTurn || game.Phase() == blackjack.Offer {
+			i := game.Turn
+			if bot := seats[i].bot; bot != nil {
+				if game.Phase() == blackjack.PlayerTurn {
+					hand := game.Hand()
+					fmt.Printf("%sHand (%d): %s\n", t.label(i, game.Seat().Active), hand.Cards.Value(), hand.Cards)
+				}
+				action := bot.Play(game)
+				fmt.Printf("%sChooses to %s.\n", t.label(i, game.Seat().Active), verb(action))
+				if err := game.Do(action); err != nil {
+					// a broken bot stands rather than ending the round for everyone
+					fallback := blackjack.Stand
+					if game.Phase() == blackjack.Offer {
+						fallback = blackjack.Decline
+					}
+					fmt.Printf("Error with the %v bot: %v. It will %s instead.\n", seats[i].name, err, verb(fallback))
+					game.Do(fallback)
+				}
+				t.show(game.Events())
+				continue
+			}
 
-	playerTurn:
-		for {
-			fmt.Printf("Your hand (%d): %s\n", playerHand.calculateValue(), playerHand)
-			fmt.Print("What will you do? (hit/stand/double/split): ")
+			hand := game.Hand()
+			if game.Phase() == blackjack.Offer {
+				fmt.Printf("%sDealer shows %v and will check for blackjack.\n", t.seatLabel(i), game.Upcard())
+			} else {
+				fmt.Printf("%sYour hand (%d): %s\n", t.label(i, game.Seat().Active), hand.Cards.Value(), hand.Cards)
+			}
+			actions := actionList(game.Available(), "/")
+			if advisor != nil {
+				actions += "/hint"
+			}
+			fmt.Printf("What will you do? (%s): ", actions)
 
-			action, _ := reader.ReadString('\n')
-			action = strings.TrimSpace(action)
-			switch action {
-			case "hit":
-				playerHand = append(playerHand, deck.drawCard())
-				if playerHand.calculateValue() > 21 {
-					fmt.Printf("Busted! Your hand (%d): %s\n", playerHand.calculateValue(), playerHand)
-					break playerTurn
+			input, _ := reader.ReadString('\n')
+			action := blackjack.Action(strings.TrimSpace(input))
+			if advisor != nil && action == "hint" {
+				advisor.hint(game)
+				continue
+			}
+			if game.Phase() == blackjack.Offer && play(action) {
+				// playing on declines the offers
+				if advisor != nil {
+					advisor.decide(game)(blackjack.Decline)
 				}
-			case "stand":
-				break playerTurn
-			case "double":
-				if len(playerHand) == 2 && balance >= bet {
-					balance -= bet
-					bet *= 2
-					playerHand = append(playerHand, deck.drawCard())
-					if playerHand.calculateValue() > 21 {
-						fmt.Printf("Busted! Your hand (%d): %s\n", playerHand.calculateValue(), playerHand)
-					}
-					break playerTurn
+				game.Decline()
+				t.show(game.Events())
+				if game.Phase() != blackjack.PlayerTurn || game.Turn != i {
+					continue
+				}
+			}
+			record := func(blackjack.Action) {}
+			if advisor != nil {
+				record = advisor.decide(game)
+			}
+			if err := game.Do(action); err != nil {
+				if errors.Is(err, blackjack.ErrPhase) || !known(action) {
+					fmt.Printf("Invalid action. Please type %s.\n", actionList(game.Available(), ", "))
 				} else {
-					fmt.Println("Cannot double: not enough balance or hand is not eligible.")
+					fmt.Printf("Cannot %s: %v.\n", verb(action), err)
 				}
-			case "split":
-				fmt.Println("Split not implemented.")
-			default:
-				fmt.Println("Invalid action. Please type hit, stand, double, or split.")
+				continue
 			}
+			record(action)
+			t.show(game.Events())
+		}
 
-			if len(deck) < 10 {
-				deck = newDeck().shuffle()
+		if *profiles != "" {
+			for i, s := range seats {
+				if s.profile != nil {
+					s.profile.Log(game, i, time.Now())
+				}
+			}
+			if err := book.Save(*profiles); err != nil {
+				fmt.Println("Error saving profiles:", err)
+				os.Exit(1)
 			}
 		}
+		fmt.Println()
+	}
 
-		if playerHand.calculateValue() <= 21 {
-			for dealerHand.calculateValue() < 17 {
-				dealerHand = append(dealerHand, deck.drawCard())
-			}
-			fmt.Printf("Dealer's hand (%d): %s\n", dealerHand.calculateValue(), dealerHand)
+	if advisor != nil {
+		advisor.report()
+	}
+	if len(seats) == 1 {
+		fmt.Println("Thank you for playing! Your final balance is:", game.Seats[0].Balance)
+		return
+	}
+	fmt.Println("Thank you for playing! Final balances:")
+	for i, s := range game.Seats {
+		fmt.Printf("%s$%d\n", t.seatLabel(i), s.Balance)
+	}
+}
+
+// askBet asks a human seat for a bet until it gets one within balance,
+// or 0 to leave the table.
+func askBet(reader *bufio.Reader, label string, balance int) int {
+	for {
+		fmt.Printf("%sCurrent balance: $%d\n", label, balance)
+		fmt.Print("Enter your bet (q to quit): ")
+
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+		if input == "q" {
+			return 0
 		}
+		bet, err := strconv.Atoi(input)
+		if err != nil || bet <= 0 || bet > balance {
+			fmt.Println("Invalid bet amount. Try again.")
+			continue
+		}
+		return bet
+	}
+}
 
-		playerValue := playerHand.calculateValue()
-		dealerValue := dealerHand.calculateValue()
-		switch {
-		case playerValue > 21:
-			fmt.Println("You busted and lost the bet.")
-		case dealerValue > 21 || playerValue > dealerValue:
-			fmt.Printf("You won $%d!\n", bet*2)
-			balance += bet * 2
-		case dealerValue == playerValue:
-			fmt.Println("Push. You got your bet back.")
-			balance += bet
-		default:
-			fmt.Println("Dealer wins. You lost the bet.")
+// show prints what happened in the game and passes it on to the bots.
+// Cards dealt to a hand are shown with it before the next decision,
+// except for hands that take no more decisions: doubled hands and split
+// aces.
+func (t *table) show(events []blackjack.Event) {
+	t.tell(events)
+	game := t.game
+	type key struct{ seat, hand int }
+	doubled := map[key]bool{}
+	for _, e := range events {
+		l := t.label(e.Seat, e.Hand)
+		sl := t.seatLabel(e.Seat)
+		switch e.Kind {
+		case blackjack.HandDoubled:
+			doubled[key{e.Seat, e.Hand}] = true
+		case blackjack.PlayerCard:
+			hand := game.Seats[e.Seat].Hands[e.Hand]
+			if (doubled[key{e.Seat, e.Hand}] && !hand.Cards.Busted()) || (hand.SplitAces && len(hand.Cards) == 2) {
+				fmt.Printf("%sYour hand (%d): %s\n", l, hand.Cards.Value(), hand.Cards)
+			}
+		case blackjack.HandBusted:
+			hand := game.Seats[e.Seat].Hands[e.Hand].Cards
+			fmt.Printf("%sBusted! Your hand (%d): %s\n", l, hand.Value(), hand)
+		case blackjack.HandSplit:
+			fmt.Printf("%sSplit into %d hands.\n", sl, e.Amount)
+		case blackjack.HandSurrendered:
+			fmt.Printf("%sYou surrendered.\n", l)
+		case blackjack.InsuranceTaken:
+			fmt.Printf("%sInsurance bet: $%d.\n", sl, e.Amount)
+		case blackjack.DealerStands:
+			fmt.Printf("Dealer's hand (%d): %s\n", e.Amount, game.Dealer)
+		case blackjack.HoleRevealed:
+			if game.Dealer.IsBlackjack() {
+				fmt.Printf("Dealer's hand (%d): %s\n", game.Dealer.Value(), game.Dealer)
+			}
+		case blackjack.EvenMoneyTaken:
+			fmt.Printf("%sYou took even money.\n", sl)
+		case blackjack.DealerBlackjack:
+			fmt.Println("Dealer has blackjack!")
+		case blackjack.NoDealerBlackjack:
+			fmt.Println("Dealer does not have blackjack.")
+		case blackjack.InsuranceSettled:
+			if e.Amount > 0 {
+				fmt.Printf("%sInsurance pays $%d.\n", sl, e.Amount)
+			} else {
+				fmt.Printf("%sInsurance lost.\n", sl)
+			}
+		case blackjack.HandSettled:
+			switch e.Outcome {
+			case blackjack.Bust:
+				fmt.Printf("%sYou busted and lost the bet.\n", l)
+			case blackjack.Win:
+				fmt.Printf("%sYou won $%d!\n", l, e.Amount)
+			case blackjack.Blackjack:
+				fmt.Printf("%sBlackjack! You won $%d!\n", l, e.Amount)
+			case blackjack.Push:
+				fmt.Printf("%sPush. You got your bet back.\n", l)
+			case blackjack.Surrendered:
+				fmt.Printf("%sSurrendered. You got $%d back.\n", l, e.Amount)
+			default:
+				fmt.Printf("%sDealer wins. You lost the bet.\n", l)
+			}
 		}
+	}
+}
 
-		fmt.Println()
+func actionList(actions []blackjack.Action, sep string) string {
+	names := make([]string, len(actions))
+	for i, a := range actions {
+		names[i] = string(a)
 	}
+	return strings.Join(names, sep)
+}
 
-	fmt.Println("Thank you for playing! Your final balance is:", balance)
+// play reports whether a is played on a hand rather than an offer.
+func play(a blackjack.Action) bool {
+	switch a {
+	case blackjack.Hit, blackjack.Stand, blackjack.Double, blackjack.Split:
+		return true
+	}
+	return false
+}
+
+// verb phrases an action for messages.
+func verb(a blackjack.Action) string {
+	switch a {
+	case blackjack.Insurance:
+		return "take insurance"
+	case blackjack.EvenMoney:
+		return "take even money"
+	}
+	return string(a)
+}
+
+func known(a blackjack.Action) bool {
+	switch a {
+	case blackjack.Surrender, blackjack.Insurance, blackjack.EvenMoney, blackjack.Decline:
+		return true
+	}
+	return play(a)
 }
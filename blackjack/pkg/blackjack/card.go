@@ -0,0 +1,129 @@
+package blackjack
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var suits = []string{"Hearts", "Diamonds", "Clubs", "Spades"}
+var values = []string{"2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K", "A"}
+
+// Card is a playing card. Value is the rank: 2-10, J, Q, K or A.
+type Card struct {
+	Suit  string
+	Value string
+}
+
+func (c Card) String() string {
+	return fmt.Sprintf("%s of %s", c.Value, c.Suit)
+}
+
+// Points is the card's blackjack value, counting an ace as 11.
+func (c Card) Points() int {
+	switch c.Value {
+	case "A":
+		return 11
+	case "K", "Q", "J":
+		return 10
+	default:
+		val, _ := strconv.Atoi(c.Value)
+		return val
+	}
+}
+
+// Hand is a set of cards.
+type Hand []Card
+
+// Value is the best total of the hand, counting aces as 1 where 11 would
+// bust it.
+func (h Hand) Value() int {
+	total, _ := h.value()
+	return total
+}
+
+// Soft reports whether an ace in the hand counts as 11.
+func (h Hand) Soft() bool {
+	_, soft := h.value()
+	return soft
+}
+
+func (h Hand) value() (int, bool) {
+	totalValue := 0
+	aces := 0
+	for _, card := range h {
+		if card.Value == "A" {
+			aces++
+		}
+		totalValue += card.Points()
+	}
+	for aces > 0 && totalValue > 21 {
+		totalValue -= 10
+		aces--
+	}
+	return totalValue, aces > 0
+}
+
+// Busted reports whether the hand is over 21.
+func (h Hand) Busted() bool {
+	return h.Value() > 21
+}
+
+// IsBlackjack reports whether the hand is an ace and a ten valued card.
+func (h Hand) IsBlackjack() bool {
+	return len(h) == 2 && h.Value() == 21
+}
+
+// IsPair reports whether the hand is two cards of equal value, like two 8s
+// or a king and a jack.
+func (h Hand) IsPair() bool {
+	return len(h) == 2 && h[0].Points() == h[1].Points()
+}
+
+func (h Hand) String() string {
+	var cards []string
+	for _, card := range h {
+		cards = append(cards, card.String())
+	}
+	return strings.Join(cards, ", ")
+}
+
+// Source deals cards to a game.
+type Source interface {
+	Draw() Card
+}
+
+// A Shuffler is a Source that takes back the cards of each round and may
+// be shuffled before the next one.
+type Shuffler interface {
+	Source
+	// Discard takes back cards that were in play.
+	Discard(cards ...Card)
+	// ShuffleIfDue shuffles if the cut card has come out, reporting
+	// whether it did.
+	ShuffleIfDue() bool
+}
+
+// newDecks returns n decks in order.
+func newDecks(n int) []Card {
+	cards := make([]Card, 0, n*52)
+	for i := 0; i < n; i++ {
+		for _, suit := range suits {
+			for _, value := range values {
+				cards = append(cards, Card{Suit: suit, Value: value})
+			}
+		}
+	}
+	return cards
+}
+
+// Stacked deals the given cards in order, for tests and replays. It
+// panics when it runs out.
+type Stacked []Card
+
+// Draw takes the next card.
+func (s *Stacked) Draw() Card {
+	card := (*s)[0]
+	*s = (*s)[1:]
+	return card
+}
@@ -0,0 +1,128 @@
+package blackjack
+
+import (
+	"fmt"
+	"strings"
+)
+
+// System is a card counting system: the tag added to the count for each
+// card value.
+type System struct {
+	Name string
+	Tags map[string]int
+}
+
+// HiLo counts low cards +1 and tens and aces -1.
+var HiLo = System{
+	Name: "Hi-Lo",
+	Tags: map[string]int{
+		"2": 1, "3": 1, "4": 1, "5": 1, "6": 1,
+		"10": -1, "J": -1, "Q": -1, "K": -1, "A": -1,
+	},
+}
+
+// KO, Knock-Out, is Hi-Lo with 7s counted +1. It is unbalanced, so it is
+// played on the running count alone.
+var KO = System{
+	Name: "KO",
+	Tags: map[string]int{
+		"2": 1, "3": 1, "4": 1, "5": 1, "6": 1, "7": 1,
+		"10": -1, "J": -1, "Q": -1, "K": -1, "A": -1,
+	},
+}
+
+// OmegaII is a level two system that leaves aces out.
+var OmegaII = System{
+	Name: "Omega II",
+	Tags: map[string]int{
+		"2": 1, "3": 1, "7": 1,
+		"4": 2, "5": 2, "6": 2,
+		"9":  -1,
+		"10": -2, "J": -2, "Q": -2, "K": -2,
+	},
+}
+
+// Systems are the counting systems by name.
+var Systems = []System{HiLo, KO, OmegaII}
+
+// SystemByName finds a system, ignoring case, spaces and dashes.
+func SystemByName(name string) (System, error) {
+	key := func(s string) string {
+		return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(s))
+	}
+	var names []string
+	for _, s := range Systems {
+		if key(s.Name) == key(name) {
+			return s, nil
+		}
+		names = append(names, s.Name)
+	}
+	return System{}, fmt.Errorf("unknown counting system %q, want one of %v", name, strings.Join(names, ", "))
+}
+
+// deckTotal is the count after a whole deck: 0 for a balanced system.
+func (s System) deckTotal() int {
+	total := 0
+	for _, tag := range s.Tags {
+		total += 4 * tag
+	}
+	return total
+}
+
+// Balanced reports whether a full deck counts to zero, so the running
+// count is divided by the decks left to get a true count.
+func (s System) Balanced() bool {
+	return s.deckTotal() == 0
+}
+
+// Start is the count to start a shoe of decks decks at: 0 for balanced
+// systems and 4 - 4 × decks for KO, so a whole shoe counts up to the same
+// total as a single deck.
+func (s System) Start(decks int) int {
+	return -s.deckTotal() * (decks - 1)
+}
+
+// Count keeps the running count of the cards seen since the last shuffle.
+type Count struct {
+	System  System
+	Running int
+	// Decks is the size of the shoe, for the start of unbalanced counts.
+	Decks int
+}
+
+// NewCount returns a count at the start of a fresh shoe.
+func NewCount(system System, decks int) Count {
+	return Count{System: system, Running: system.Start(decks), Decks: decks}
+}
+
+// Reset starts the count over for a fresh shoe.
+func (c *Count) Reset() {
+	c.Running = c.System.Start(c.Decks)
+}
+
+// Add counts cards.
+func (c *Count) Add(cards ...Card) {
+	for _, card := range cards {
+		c.Running += c.System.Tags[card.Value]
+	}
+}
+
+// True is the running count per deck left to deal.
+func (c *Count) True(decksLeft float64) float64 {
+	if decksLeft < 0.5 {
+		decksLeft = 0.5
+	}
+	return float64(c.Running) / decksLeft
+}
+
+// See counts the cards shown by events and starts over on a shuffle.
+func (c *Count) See(events []Event) {
+	for _, e := range events {
+		switch e.Kind {
+		case Shuffled:
+			c.Reset()
+		case PlayerCard, DealerCard, HoleRevealed:
+			c.Add(e.Card)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package blackjack
+
+// EventKind says what happened.
+type EventKind int
+
+const (
+	// Shuffled is the shoe being shuffled before a round.
+	Shuffled EventKind = iota
+	// PlayerCard is a card dealt to seat Seat's hand Hand.
+	PlayerCard
+	// DealerCard is a face up card dealt to the dealer.
+	DealerCard
+	// HoleCard is the dealer's face down card.
+	HoleCard
+	// HoleRevealed turns the hole card, Card, over.
+	HoleRevealed
+	// Turn moves play to seat Seat's hand Hand.
+	Turn
+	HandBusted
+	HandStood
+	// HandDoubled doubles the hand's bet to Amount.
+	HandDoubled
+	// HandSplit splits the hand; Amount is the number of hands now.
+	HandSplit
+	HandSurrendered
+	// InsuranceTaken places an insurance bet of Amount.
+	InsuranceTaken
+	// EvenMoneyTaken takes 1:1 for the seat's blackjack.
+	EvenMoneyTaken
+	// DealerBlackjack is the dealer peeking and finding blackjack.
+	DealerBlackjack
+	// NoDealerBlackjack is the dealer peeking and finding no blackjack.
+	NoDealerBlackjack
+	// InsuranceSettled pays Amount for the seat's insurance bet, 0 if it
+	// lost.
+	InsuranceSettled
+	// DealerStands ends the dealer's hand with a total of Amount.
+	DealerStands
+	// HandSettled pays Amount for the hand with Outcome.
+	HandSettled
+)
+
+// Event is a change in the game. Seat and Hand say whose hand it is about.
+type Event struct {
+	Kind    EventKind
+	Seat    int
+	Hand    int
+	Card    Card
+	Amount  int
+	Outcome Outcome
+}
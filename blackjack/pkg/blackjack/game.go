@@ -0,0 +1,661 @@
+// Package blackjack is the blackjack game engine: a state machine for a
+// table of up to MaxSeats players against the dealer that front ends,
+// bots and simulators drive with Deal, Insurance, EvenMoney, Decline, Hit,
+// Stand, Double, Split and Surrender. Every change is reported as an Event
+// and hands are paid by Settle.
+package blackjack
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Phase is where a round is.
+type Phase int
+
+const (
+	// Betting waits for Deal.
+	Betting Phase = iota
+	// Offer waits for each seat in turn to take or decline insurance, even
+	// money or early surrender before the dealer peeks for blackjack.
+	Offer
+	// PlayerTurn waits for the seat to act to play its active hand.
+	PlayerTurn
+	// Settled means the round is over and paid; Deal starts the next one.
+	Settled
+)
+
+// Action is something the player can do.
+type Action string
+
+const (
+	Hit       Action = "hit"
+	Stand     Action = "stand"
+	Double    Action = "double"
+	Split     Action = "split"
+	Surrender Action = "surrender"
+	Insurance Action = "insurance"
+	EvenMoney Action = "even-money"
+	Decline   Action = "decline"
+)
+
+// Errors returned for actions that are not allowed.
+var (
+	ErrPhase        = errors.New("not allowed at this point of the round")
+	ErrBet          = errors.New("bet must not be negative or more than the balance")
+	ErrNoBets       = errors.New("no seat placed a bet")
+	ErrFunds        = errors.New("not enough balance")
+	ErrCannotDouble = errors.New("only the first two cards of a hand can be doubled")
+	ErrDoubleTotal  = errors.New("the table does not allow doubling this total")
+	ErrDoubleSplit  = errors.New("the table does not allow doubling after a split")
+	ErrCannotSplit  = errors.New("only a pair of equal value cards can be split")
+	ErrMaxHands     = errors.New("no more hands allowed")
+	ErrSurrender    = errors.New("surrender is only allowed on the first two cards")
+	ErrInsurance    = errors.New("insurance is only offered against a dealer ace before the dealer peeks")
+	ErrEvenMoney    = errors.New("even money is only offered on a blackjack against a dealer ace")
+)
+
+// PlayerHand is one of a seat's hands in a round.
+type PlayerHand struct {
+	Cards Hand
+	Bet   int
+	// Split is set on hands made by splitting; their 21 is not a natural.
+	Split bool
+	// SplitAces hands got one card after splitting and cannot act.
+	SplitAces   bool
+	Doubled     bool
+	Surrendered bool
+	// EvenMoney hands took a 1:1 payout for a blackjack against an ace.
+	EvenMoney bool
+	// Done is set once the hand has stood, busted, doubled or surrendered.
+	Done bool
+	// Actions are the seat's decisions on the hand, offers included. Hands
+	// made by splitting start with the actions of the hand they came from.
+	Actions []Action
+	// Outcome and Payout are set when the hand is settled.
+	Outcome Outcome
+	Payout  int
+}
+
+// MaxSeats is how many seats a table has.
+const MaxSeats = 7
+
+// Seat is one player at the table.
+type Seat struct {
+	Balance int
+	// Hands is empty when the seat sits the round out.
+	Hands []*PlayerHand
+	// Active is the index of the hand being played.
+	Active int
+	// InsuranceBet is the side bet taken with Insurance.
+	InsuranceBet int
+
+	acted bool
+}
+
+// Playing reports whether the seat has a bet on the round.
+func (s *Seat) Playing() bool {
+	return len(s.Hands) > 0
+}
+
+// Hand returns the active hand.
+func (s *Seat) Hand() *PlayerHand {
+	return s.Hands[s.Active]
+}
+
+// natural reports whether the seat has a blackjack on the dealt hand.
+func (s *Seat) natural() bool {
+	return len(s.Hands) == 1 && !s.Hands[0].Split && s.Hands[0].Cards.IsBlackjack()
+}
+
+// Game is a table of seats against the dealer. The seats act in order,
+// each playing all its hands before the next. The zero value is not
+// usable; create one with NewGame.
+type Game struct {
+	Rules  Rules
+	Seats  []*Seat
+	Dealer Hand
+	// Turn is the index of the seat to act.
+	Turn int
+	// Peeked is set once the dealer has checked the hole card.
+	Peeked bool
+
+	source Source
+	phase  Phase
+	events []Event
+}
+
+// NewGame returns a game waiting for the first bets, with a seat for each
+// starting balance.
+func NewGame(rules Rules, source Source, balances ...int) *Game {
+	g := &Game{Rules: rules, source: source}
+	for _, balance := range balances {
+		g.Seats = append(g.Seats, &Seat{Balance: balance})
+	}
+	return g
+}
+
+// Phase returns where the round is.
+func (g *Game) Phase() Phase {
+	return g.phase
+}
+
+// Events returns what happened since the last call.
+func (g *Game) Events() []Event {
+	events := g.events
+	g.events = nil
+	return events
+}
+
+func (g *Game) emit(e Event) {
+	g.events = append(g.events, e)
+}
+
+// Seat returns the seat to act.
+func (g *Game) Seat() *Seat {
+	return g.Seats[g.Turn]
+}
+
+// Hand returns the active hand of the seat to act.
+func (g *Game) Hand() *PlayerHand {
+	return g.Seat().Hand()
+}
+
+// Upcard is the dealer's face up card.
+func (g *Game) Upcard() Card {
+	return g.Dealer[0]
+}
+
+// Deal takes a bet for each seat, 0 to sit the round out, and deals like
+// a casino: one card to each seat in order, the dealer's upcard, a second
+// card to each seat and the dealer's hole card. A Shuffler source whose
+// cut card is out is shuffled first. Against an ace, or a ten when early
+// surrender is allowed, the round waits in the Offer phase for each seat;
+// otherwise the dealer peeks right away.
+func (g *Game) Deal(bets ...int) error {
+	if g.phase == PlayerTurn || g.phase == Offer {
+		return ErrPhase
+	}
+	if len(bets) != len(g.Seats) {
+		return fmt.Errorf("want %d bets, one for each seat, not %d", len(g.Seats), len(bets))
+	}
+	total := 0
+	for i, bet := range bets {
+		if bet < 0 || bet > g.Seats[i].Balance {
+			return ErrBet
+		}
+		total += bet
+	}
+	if total == 0 {
+		return ErrNoBets
+	}
+	if shoe, ok := g.source.(Shuffler); ok && shoe.ShuffleIfDue() {
+		g.emit(Event{Kind: Shuffled})
+	}
+	for i, seat := range g.Seats {
+		seat.Hands = nil
+		if bets[i] > 0 {
+			seat.Balance -= bets[i]
+			seat.Hands = []*PlayerHand{{Bet: bets[i]}}
+		}
+		seat.Active = 0
+		seat.InsuranceBet = 0
+		seat.acted = false
+	}
+	g.Dealer = nil
+	g.Turn = 0
+	g.Peeked = false
+	g.phase = PlayerTurn
+
+	g.dealRound()
+	g.Dealer = append(g.Dealer, g.source.Draw())
+	g.emit(Event{Kind: DealerCard, Card: g.Dealer[0]})
+	g.dealRound()
+	g.Dealer = append(g.Dealer, g.source.Draw())
+	g.emit(Event{Kind: HoleCard})
+
+	upcard := g.Upcard()
+	if upcard.Value == "A" || (upcard.Points() == 10 && g.Rules.Surrender == EarlySurrender) {
+		g.phase = Offer
+		g.Turn = g.nextPlaying(-1)
+		return nil
+	}
+	g.peek()
+	return nil
+}
+
+// dealRound deals a card to each seat playing the round.
+func (g *Game) dealRound() {
+	for i, seat := range g.Seats {
+		if seat.Playing() {
+			g.dealTo(i, 0)
+		}
+	}
+}
+
+// nextPlaying returns the index of the first seat after seat i playing
+// the round, or -1 if there is none.
+func (g *Game) nextPlaying(i int) int {
+	for i++; i < len(g.Seats); i++ {
+		if g.Seats[i].Playing() {
+			return i
+		}
+	}
+	return -1
+}
+
+// offered moves the offers on to the next seat, or has the dealer peek
+// once every seat has answered.
+func (g *Game) offered() {
+	if next := g.nextPlaying(g.Turn); next >= 0 {
+		g.Turn = next
+		g.emit(Event{Kind: Turn, Seat: next})
+		return
+	}
+	g.peek()
+}
+
+// peek has the dealer check the hole card when the upcard is an ace or a
+// ten. Insurance is settled here, before anyone plays. A dealer blackjack
+// ends the round at once; otherwise naturals, even money and early
+// surrenders are paid and the first seat with a hand to play acts.
+func (g *Game) peek() {
+	g.phase = PlayerTurn
+	if g.Upcard().Value == "A" || g.Upcard().Points() == 10 {
+		g.Peeked = true
+		if g.Dealer.IsBlackjack() {
+			g.emit(Event{Kind: DealerBlackjack})
+		} else {
+			g.emit(Event{Kind: NoDealerBlackjack})
+		}
+	}
+	for i, seat := range g.Seats {
+		if seat.InsuranceBet == 0 {
+			continue
+		}
+		if g.Dealer.IsBlackjack() {
+			seat.Balance += seat.InsuranceBet * 3
+			g.emit(Event{Kind: InsuranceSettled, Seat: i, Amount: seat.InsuranceBet * 3})
+		} else {
+			g.emit(Event{Kind: InsuranceSettled, Seat: i})
+		}
+	}
+	if g.Dealer.IsBlackjack() {
+		g.finishRound()
+		return
+	}
+	for i, seat := range g.Seats {
+		if !seat.Playing() {
+			continue
+		}
+		if hand := seat.Hands[0]; seat.natural() || hand.EvenMoney || hand.Surrendered {
+			hand.Done = true
+			g.settle(i, 0)
+		}
+	}
+	g.Turn = 0
+	g.next()
+}
+
+func (g *Game) dealTo(seat, hand int) {
+	card := g.source.Draw()
+	h := g.Seats[seat].Hands[hand]
+	h.Cards = append(h.Cards, card)
+	g.emit(Event{Kind: PlayerCard, Seat: seat, Hand: hand, Card: card})
+}
+
+// Available returns the actions allowed now for the seat to act: the
+// offers before the dealer peeks, or the plays on its active hand.
+func (g *Game) Available() []Action {
+	var actions []Action
+	switch g.phase {
+	case Offer:
+		for _, a := range []Action{Insurance, EvenMoney, Surrender} {
+			if g.check(a) == nil {
+				actions = append(actions, a)
+			}
+		}
+		return append(actions, Decline)
+	case PlayerTurn:
+		actions = []Action{Hit, Stand}
+		for _, a := range []Action{Double, Split, Surrender} {
+			if g.check(a) == nil {
+				actions = append(actions, a)
+			}
+		}
+	}
+	return actions
+}
+
+// check returns why an action is not allowed, or nil.
+func (g *Game) check(a Action) error {
+	if g.phase == Offer {
+		return g.checkOffer(a)
+	}
+	if g.phase != PlayerTurn {
+		return ErrPhase
+	}
+	seat := g.Seat()
+	hand := seat.Hand()
+	first := len(seat.Hands) == 1 && len(hand.Cards) == 2 && !seat.acted
+	switch a {
+	case Double:
+		if len(hand.Cards) != 2 {
+			return ErrCannotDouble
+		}
+		if !g.Rules.Double.Allows(hand.Cards.Value()) {
+			return ErrDoubleTotal
+		}
+		if hand.Split && !g.Rules.DoubleAfterSplit {
+			return ErrDoubleSplit
+		}
+		if seat.Balance < hand.Bet {
+			return ErrFunds
+		}
+	case Split:
+		if !hand.Cards.IsPair() {
+			return ErrCannotSplit
+		}
+		if len(seat.Hands) >= g.Rules.MaxHands {
+			return ErrMaxHands
+		}
+		if seat.Balance < hand.Bet {
+			return ErrFunds
+		}
+	case Surrender:
+		if !g.Rules.Surrender.Offered() || !first {
+			return ErrSurrender
+		}
+	case Insurance:
+		return ErrInsurance
+	case EvenMoney:
+		return ErrEvenMoney
+	case Decline:
+		return ErrPhase
+	}
+	return nil
+}
+
+// checkOffer is check for the Offer phase.
+func (g *Game) checkOffer(a Action) error {
+	seat := g.Seat()
+	hand := seat.Hand()
+	ace := g.Upcard().Value == "A"
+	switch a {
+	case Insurance:
+		if !ace || seat.natural() {
+			return ErrInsurance
+		}
+		if seat.Balance < hand.Bet/2 || hand.Bet/2 == 0 {
+			return ErrFunds
+		}
+	case EvenMoney:
+		if !ace || !seat.natural() {
+			return ErrEvenMoney
+		}
+	case Surrender:
+		if g.Rules.Surrender != EarlySurrender {
+			return ErrSurrender
+		}
+	case Decline:
+	default:
+		return ErrPhase
+	}
+	return nil
+}
+
+// Do performs an action by name.
+func (g *Game) Do(a Action) error {
+	switch a {
+	case Hit:
+		return g.Hit()
+	case Stand:
+		return g.Stand()
+	case Double:
+		return g.Double()
+	case Split:
+		return g.Split()
+	case Surrender:
+		return g.Surrender()
+	case Insurance:
+		return g.Insurance()
+	case EvenMoney:
+		return g.EvenMoney()
+	case Decline:
+		return g.Decline()
+	}
+	return fmt.Errorf("unknown action %q", a)
+}
+
+// Hit draws a card to the active hand.
+func (g *Game) Hit() error {
+	if err := g.check(Hit); err != nil {
+		return err
+	}
+	seat := g.Seat()
+	seat.acted = true
+	g.record(Hit)
+	g.dealTo(g.Turn, seat.Active)
+	if seat.Hand().Cards.Busted() {
+		g.emit(Event{Kind: HandBusted, Seat: g.Turn, Hand: seat.Active})
+		g.finishHand()
+	}
+	return nil
+}
+
+// Stand ends the active hand.
+func (g *Game) Stand() error {
+	if err := g.check(Stand); err != nil {
+		return err
+	}
+	seat := g.Seat()
+	seat.acted = true
+	g.record(Stand)
+	g.emit(Event{Kind: HandStood, Seat: g.Turn, Hand: seat.Active})
+	g.finishHand()
+	return nil
+}
+
+// Double doubles the bet, draws exactly one card and ends the hand.
+func (g *Game) Double() error {
+	if err := g.check(Double); err != nil {
+		return err
+	}
+	seat := g.Seat()
+	seat.acted = true
+	g.record(Double)
+	hand := seat.Hand()
+	seat.Balance -= hand.Bet
+	hand.Bet *= 2
+	hand.Doubled = true
+	g.emit(Event{Kind: HandDoubled, Seat: g.Turn, Hand: seat.Active, Amount: hand.Bet})
+	g.dealTo(g.Turn, seat.Active)
+	if hand.Cards.Busted() {
+		g.emit(Event{Kind: HandBusted, Seat: g.Turn, Hand: seat.Active})
+	}
+	g.finishHand()
+	return nil
+}
+
+// Split turns a pair into two hands with the same bet. The active hand
+// gets a second card now and the new one when it is played. Split aces
+// get one card each and stand.
+func (g *Game) Split() error {
+	if err := g.check(Split); err != nil {
+		return err
+	}
+	seat := g.Seat()
+	seat.acted = true
+	g.record(Split)
+	hand := seat.Hand()
+	seat.Balance -= hand.Bet
+	aces := hand.Cards[0].Value == "A"
+	second := &PlayerHand{Cards: Hand{hand.Cards[1]}, Bet: hand.Bet, Split: true, SplitAces: aces}
+	second.Actions = append([]Action(nil), hand.Actions...)
+	hand.Cards = hand.Cards[:1]
+	hand.Split = true
+	hand.SplitAces = aces
+
+	// the new hand is played right after this one
+	i := seat.Active
+	seat.Hands = append(seat.Hands[:i+1], append([]*PlayerHand{second}, seat.Hands[i+1:]...)...)
+	g.emit(Event{Kind: HandSplit, Seat: g.Turn, Hand: i, Amount: len(seat.Hands)})
+	g.dealTo(g.Turn, i)
+	if aces {
+		g.finishHand()
+	}
+	return nil
+}
+
+// Surrender gives up the hand and half the bet. Early surrender happens
+// in the Offer phase, before the dealer peeks.
+func (g *Game) Surrender() error {
+	if err := g.check(Surrender); err != nil {
+		return err
+	}
+	seat := g.Seat()
+	seat.acted = true
+	g.record(Surrender)
+	seat.Hand().Surrendered = true
+	g.emit(Event{Kind: HandSurrendered, Seat: g.Turn, Hand: seat.Active})
+	if g.phase == Offer {
+		g.offered()
+		return nil
+	}
+	g.finishHand()
+	return nil
+}
+
+// Insurance takes a side bet of half the bet that pays 2:1 if the dealer
+// has blackjack.
+func (g *Game) Insurance() error {
+	if err := g.check(Insurance); err != nil {
+		return err
+	}
+	seat := g.Seat()
+	g.record(Insurance)
+	seat.InsuranceBet = seat.Hand().Bet / 2
+	seat.Balance -= seat.InsuranceBet
+	g.emit(Event{Kind: InsuranceTaken, Seat: g.Turn, Amount: seat.InsuranceBet})
+	g.offered()
+	return nil
+}
+
+// EvenMoney takes a 1:1 payout for a blackjack against an ace instead of
+// risking a push.
+func (g *Game) EvenMoney() error {
+	if err := g.check(EvenMoney); err != nil {
+		return err
+	}
+	g.record(EvenMoney)
+	g.Hand().EvenMoney = true
+	g.emit(Event{Kind: EvenMoneyTaken, Seat: g.Turn})
+	g.offered()
+	return nil
+}
+
+// Decline turns down the offers.
+func (g *Game) Decline() error {
+	if err := g.check(Decline); err != nil {
+		return err
+	}
+	g.record(Decline)
+	g.offered()
+	return nil
+}
+
+// record adds a to the active hand's actions.
+func (g *Game) record(a Action) {
+	hand := g.Hand()
+	hand.Actions = append(hand.Actions, a)
+}
+
+// finishHand marks the active hand done and moves on.
+func (g *Game) finishHand() {
+	g.Hand().Done = true
+	g.next()
+}
+
+// next moves play to the first hand left to play from the active hand of
+// the seat to act on, dealing split hands their second card, or plays the
+// dealer and settles when there is none.
+func (g *Game) next() {
+	for ; g.Turn < len(g.Seats); g.Turn++ {
+		seat := g.Seats[g.Turn]
+		for ; seat.Active < len(seat.Hands); seat.Active++ {
+			hand := seat.Hand()
+			if hand.Done {
+				continue
+			}
+			if len(hand.Cards) == 1 {
+				g.dealTo(g.Turn, seat.Active)
+			}
+			if hand.SplitAces {
+				hand.Done = true
+				continue
+			}
+			g.emit(Event{Kind: Turn, Seat: g.Turn, Hand: seat.Active})
+			return
+		}
+		// keep Active on a hand for callers looking at the seat later
+		seat.Active = max(0, len(seat.Hands)-1)
+	}
+	g.Turn = 0
+	g.finishRound()
+}
+
+// finishRound plays the dealer's hand if any hand is still live and pays
+// every hand not paid yet.
+func (g *Game) finishRound() {
+	g.emit(Event{Kind: HoleRevealed, Card: g.Dealer[1]})
+	// the dealer only plays against hands still waiting on the total
+	live := false
+	for _, seat := range g.Seats {
+		for _, hand := range seat.Hands {
+			if hand.Outcome == Pending && !hand.Surrendered && !hand.Cards.Busted() {
+				live = true
+			}
+		}
+	}
+	if live && !g.Dealer.IsBlackjack() {
+		for g.dealerHits() {
+			card := g.source.Draw()
+			g.Dealer = append(g.Dealer, card)
+			g.emit(Event{Kind: DealerCard, Card: card})
+		}
+		g.emit(Event{Kind: DealerStands, Amount: g.Dealer.Value()})
+	}
+
+	for i, seat := range g.Seats {
+		for j, hand := range seat.Hands {
+			if hand.Outcome == Pending {
+				g.settle(i, j)
+			}
+		}
+	}
+	if shoe, ok := g.source.(Shuffler); ok {
+		for _, seat := range g.Seats {
+			for _, hand := range seat.Hands {
+				shoe.Discard(hand.Cards...)
+			}
+		}
+		shoe.Discard(g.Dealer...)
+	}
+	g.phase = Settled
+}
+
+// settle pays a hand.
+func (g *Game) settle(seat, i int) {
+	s := g.Seats[seat]
+	hand := s.Hands[i]
+	hand.Outcome, hand.Payout = Settle(*hand, g.Dealer, g.Rules)
+	s.Balance += hand.Payout
+	g.emit(Event{Kind: HandSettled, Seat: seat, Hand: i, Outcome: hand.Outcome, Amount: hand.Payout})
+}
+
+// dealerHits reports whether the dealer draws another card: below 17, or
+// on a soft 17 when the table says so.
+func (g *Game) dealerHits() bool {
+	value := g.Dealer.Value()
+	return value < 17 || (value == 17 && g.Rules.HitSoft17 && g.Dealer.Soft())
+}
@@ -0,0 +1,343 @@
+package blackjack
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// stacked deals cards of the given values in order.
+func stacked(values ...string) *Stacked {
+	s := Stacked(hand(values...))
+	return &s
+}
+
+// kinds returns the kinds of the events, leaving out dealt cards.
+func kinds(events []Event) []EventKind {
+	var ks []EventKind
+	for _, e := range events {
+		if e.Kind != PlayerCard && e.Kind != DealerCard && e.Kind != HoleCard {
+			ks = append(ks, e.Kind)
+		}
+	}
+	return ks
+}
+
+// deal starts a round and fails the test if it cannot.
+func deal(t *testing.T, g *Game, bets ...int) {
+	t.Helper()
+	if err := g.Deal(bets...); err != nil {
+		t.Fatalf("Deal(%v): %v", bets, err)
+	}
+}
+
+// do performs actions and fails the test on the first one refused.
+func do(t *testing.T, g *Game, actions ...Action) {
+	t.Helper()
+	for _, a := range actions {
+		if err := g.Do(a); err != nil {
+			t.Fatalf("%v: %v", a, err)
+		}
+	}
+}
+
+func TestDealOrder(t *testing.T) {
+	g := NewGame(DefaultRules(), stacked("2", "3", "6", "4", "5", "10"), 100, 100)
+	for _, bets := range [][]int{{-5, 10}, {10, 101}} {
+		if err := g.Deal(bets...); !errors.Is(err, ErrBet) {
+			t.Errorf("Deal(%v) = %v, want %v", bets, err, ErrBet)
+		}
+	}
+	deal(t, g, 10, 20)
+
+	if got, want := g.Seats[0].Hand().Cards, hand("2", "4"); !reflect.DeepEqual(got, want) {
+		t.Errorf("seat 1 got %v, want %v", got, want)
+	}
+	if got, want := g.Seats[1].Hand().Cards, hand("3", "5"); !reflect.DeepEqual(got, want) {
+		t.Errorf("seat 2 got %v, want %v", got, want)
+	}
+	if got, want := g.Dealer, hand("6", "10"); !reflect.DeepEqual(got, want) {
+		t.Errorf("dealer got %v, want %v", got, want)
+	}
+	if g.Seats[0].Balance != 90 || g.Seats[1].Balance != 80 {
+		t.Errorf("balances %d and %d, want 90 and 80", g.Seats[0].Balance, g.Seats[1].Balance)
+	}
+	if g.Phase() != PlayerTurn || g.Turn != 0 || g.Peeked {
+		t.Errorf("phase %v, turn %d, peeked %v; want the first seat to play without a peek", g.Phase(), g.Turn, g.Peeked)
+	}
+	if err := g.Deal(10, 10); !errors.Is(err, ErrPhase) {
+		t.Errorf("Deal during a round = %v, want %v", err, ErrPhase)
+	}
+}
+
+func TestSitOut(t *testing.T) {
+	g := NewGame(DefaultRules(), stacked("2", "6", "4", "10"), 100, 100)
+	deal(t, g, 0, 10)
+
+	if g.Seats[0].Playing() || g.Seats[0].Balance != 100 {
+		t.Errorf("seat 1 sitting out is playing %v with balance %d", g.Seats[0].Playing(), g.Seats[0].Balance)
+	}
+	if got, want := g.Seats[1].Hand().Cards, hand("2", "4"); !reflect.DeepEqual(got, want) {
+		t.Errorf("seat 2 got %v, want %v", got, want)
+	}
+	if g.Turn != 1 {
+		t.Errorf("turn %d, want seat 2 to act", g.Turn)
+	}
+	if err := NewGame(DefaultRules(), stacked(), 100).Deal(0); !errors.Is(err, ErrNoBets) {
+		t.Errorf("Deal with no bets = %v, want %v", err, ErrNoBets)
+	}
+}
+
+func TestInsuranceBeforePeek(t *testing.T) {
+	// seat 1 has 19, seat 2 a natural, the dealer an ace up
+	tests := []struct {
+		name     string
+		hole     string
+		actions  []Action
+		events   []EventKind
+		phase    Phase
+		balances []int
+	}{
+		{
+			name:    "dealer blackjack",
+			hole:    "Q",
+			actions: []Action{Insurance, EvenMoney},
+			events: []EventKind{InsuranceTaken, Turn, EvenMoneyTaken, DealerBlackjack, InsuranceSettled,
+				HoleRevealed, HandSettled, HandSettled},
+			phase: Settled,
+			// the insurance pays back the lost bet, even money pays 1:1
+			balances: []int{100, 110},
+		},
+		{
+			name:    "no dealer blackjack",
+			hole:    "6",
+			actions: []Action{Insurance, Decline},
+			events:  []EventKind{InsuranceTaken, Turn, NoDealerBlackjack, InsuranceSettled, HandSettled, Turn},
+			phase:   PlayerTurn,
+			// the insurance is lost and the natural paid before seat 1 plays
+			balances: []int{85, 110},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGame(DefaultRules(), stacked("10", "A", "A", "9", "K", tt.hole), 100, 100)
+			deal(t, g, 10, 10)
+			g.Events()
+
+			if g.Phase() != Offer || g.Peeked {
+				t.Fatalf("phase %v, peeked %v; want offers before the peek", g.Phase(), g.Peeked)
+			}
+			if err := g.Hit(); !errors.Is(err, ErrPhase) {
+				t.Errorf("Hit before the peek = %v, want %v", err, ErrPhase)
+			}
+			if err := g.EvenMoney(); !errors.Is(err, ErrEvenMoney) {
+				t.Errorf("EvenMoney without a natural = %v, want %v", err, ErrEvenMoney)
+			}
+			do(t, g, tt.actions[0])
+			if g.Peeked || g.Turn != 1 {
+				t.Fatalf("peeked %v, turn %d after seat 1 answered; want seat 2 asked first", g.Peeked, g.Turn)
+			}
+			if err := g.Insurance(); !errors.Is(err, ErrInsurance) {
+				t.Errorf("Insurance on a natural = %v, want %v", err, ErrInsurance)
+			}
+			do(t, g, tt.actions[1])
+
+			if got := kinds(g.Events()); !reflect.DeepEqual(got, tt.events) {
+				t.Errorf("events %v, want %v", got, tt.events)
+			}
+			if !g.Peeked || g.Phase() != tt.phase {
+				t.Errorf("phase %v, peeked %v; want %v after the peek", g.Phase(), g.Peeked, tt.phase)
+			}
+			for i, want := range tt.balances {
+				if got := g.Seats[i].Balance; got != want {
+					t.Errorf("seat %d balance %d, want %d", i+1, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("no offers against a ten", func(t *testing.T) {
+		g := NewGame(DefaultRules(), stacked("10", "K", "9", "7"), 100)
+		deal(t, g, 10)
+		if g.Phase() != PlayerTurn || !g.Peeked {
+			t.Errorf("phase %v, peeked %v; want the dealer to peek at once", g.Phase(), g.Peeked)
+		}
+		if err := g.Insurance(); !errors.Is(err, ErrInsurance) {
+			t.Errorf("Insurance = %v, want %v", err, ErrInsurance)
+		}
+	})
+}
+
+func TestSplitAces(t *testing.T) {
+	g := NewGame(DefaultRules(), stacked("A", "10", "A", "7", "K", "5"), 100)
+	deal(t, g, 10)
+	do(t, g, Split)
+
+	// each ace gets one card and the round ends without another action
+	if g.Phase() != Settled {
+		t.Fatalf("phase %v after splitting aces, want %v", g.Phase(), Settled)
+	}
+	hands := g.Seats[0].Hands
+	if len(hands) != 2 {
+		t.Fatalf("%d hands after splitting, want 2", len(hands))
+	}
+	for i, want := range []struct {
+		cards   Hand
+		outcome Outcome
+		payout  int
+	}{
+		{hand("A", "K"), Win, 20},
+		{hand("A", "5"), Lose, 0},
+	} {
+		h := hands[i]
+		if !reflect.DeepEqual(h.Cards, want.cards) || !h.SplitAces || h.Outcome != want.outcome || h.Payout != want.payout {
+			t.Errorf("hand %d: %v %v paid %d, want %v %v paid %d", i+1, h.Cards, h.Outcome, h.Payout, want.cards, want.outcome, want.payout)
+		}
+	}
+	if g.Seats[0].Balance != 100 {
+		t.Errorf("balance %d, want 100", g.Seats[0].Balance)
+	}
+	if err := g.Hit(); !errors.Is(err, ErrPhase) {
+		t.Errorf("Hit after the round = %v, want %v", err, ErrPhase)
+	}
+}
+
+func TestSplitMaxHands(t *testing.T) {
+	rules := DefaultRules()
+	rules.MaxHands = 2
+	g := NewGame(rules, stacked("8", "6", "8", "10", "8", "2", "3"), 100)
+	deal(t, g, 10)
+	do(t, g, Split)
+
+	if got, want := g.Hand().Cards, hand("8", "8"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("first split hand %v, want %v", got, want)
+	}
+	if err := g.Split(); !errors.Is(err, ErrMaxHands) {
+		t.Errorf("Split past MaxHands = %v, want %v", err, ErrMaxHands)
+	}
+	do(t, g, Stand)
+	if g.Seat().Active != 1 || !reflect.DeepEqual(g.Hand().Cards, hand("8", "2")) {
+		t.Errorf("active hand %d with %v, want hand 2 dealt its second card", g.Seat().Active+1, g.Hand().Cards)
+	}
+}
+
+func TestDoubleRestrictions(t *testing.T) {
+	tests := []struct {
+		name    string
+		double  DoubleRule
+		cards   [2]string
+		balance int
+		hit     bool
+		want    error
+	}{
+		{"any total", DoubleAny, [2]string{"5", "3"}, 100, false, nil},
+		{"9-11 allows 9", Double9to11, [2]string{"5", "4"}, 100, false, nil},
+		{"9-11 refuses 8", Double9to11, [2]string{"5", "3"}, 100, false, ErrDoubleTotal},
+		{"9-11 refuses soft 19", Double9to11, [2]string{"A", "8"}, 100, false, ErrDoubleTotal},
+		{"10-11 allows 11", Double10to11, [2]string{"6", "5"}, 100, false, nil},
+		{"10-11 refuses 9", Double10to11, [2]string{"5", "4"}, 100, false, ErrDoubleTotal},
+		{"not enough balance", DoubleAny, [2]string{"6", "5"}, 15, false, ErrFunds},
+		{"after a hit", DoubleAny, [2]string{"2", "3"}, 100, true, ErrCannotDouble},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := DefaultRules()
+			rules.Double = tt.double
+			g := NewGame(rules, stacked(tt.cards[0], "6", tt.cards[1], "10", "2", "10", "10"), tt.balance)
+			deal(t, g, 10)
+			if tt.hit {
+				do(t, g, Hit)
+			}
+
+			err := g.Double()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Double = %v, want %v", err, tt.want)
+			}
+			if err != nil {
+				if g.Hand().Doubled || g.Hand().Bet != 10 {
+					t.Errorf("refused double changed the hand: bet %d, doubled %v", g.Hand().Bet, g.Hand().Doubled)
+				}
+				return
+			}
+			h := g.Seats[0].Hand()
+			if !h.Doubled || h.Bet != 20 || len(h.Cards) != 3 || g.Phase() != Settled {
+				t.Errorf("doubled hand has bet %d and %v, phase %v; want one card on a bet of 20 and the round over", h.Bet, h.Cards, g.Phase())
+			}
+		})
+	}
+}
+
+func TestDoubleAfterSplit(t *testing.T) {
+	for _, das := range []bool{true, false} {
+		rules := DefaultRules()
+		rules.DoubleAfterSplit = das
+		g := NewGame(rules, stacked("8", "6", "8", "10", "3", "10", "10", "10"), 100)
+		deal(t, g, 10)
+		do(t, g, Split)
+
+		var want error
+		if !das {
+			want = ErrDoubleSplit
+		}
+		if err := g.Double(); !errors.Is(err, want) {
+			t.Errorf("double after split %v: Double on 11 = %v, want %v", das, err, want)
+		}
+	}
+}
+
+func TestSurrender(t *testing.T) {
+	late := DefaultRules()
+	late.Surrender = LateSurrender
+
+	t.Run("late", func(t *testing.T) {
+		g := NewGame(late, stacked("10", "6", "6", "10"), 100)
+		deal(t, g, 10)
+		do(t, g, Surrender)
+
+		h := g.Seats[0].Hand()
+		if g.Phase() != Settled || h.Outcome != Surrendered || g.Seats[0].Balance != 95 {
+			t.Errorf("phase %v, outcome %v, balance %d; want half the bet back", g.Phase(), h.Outcome, g.Seats[0].Balance)
+		}
+		// the dealer does not play against a surrendered hand
+		if len(g.Dealer) != 2 {
+			t.Errorf("dealer drew to %v", g.Dealer)
+		}
+	})
+
+	t.Run("late after a hit", func(t *testing.T) {
+		g := NewGame(late, stacked("2", "6", "3", "10", "4"), 100)
+		deal(t, g, 10)
+		do(t, g, Hit)
+		if err := g.Surrender(); !errors.Is(err, ErrSurrender) {
+			t.Errorf("Surrender after a hit = %v, want %v", err, ErrSurrender)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		g := NewGame(DefaultRules(), stacked("10", "6", "6", "10"), 100)
+		deal(t, g, 10)
+		if err := g.Surrender(); !errors.Is(err, ErrSurrender) {
+			t.Errorf("Surrender = %v, want %v", err, ErrSurrender)
+		}
+	})
+
+	t.Run("early against dealer blackjack", func(t *testing.T) {
+		rules := DefaultRules()
+		rules.Surrender = EarlySurrender
+		g := NewGame(rules, stacked("10", "K", "6", "A"), 100)
+		deal(t, g, 10)
+
+		if g.Phase() != Offer {
+			t.Fatalf("phase %v, want early surrender offered against a ten", g.Phase())
+		}
+		if got, want := g.Available(), []Action{Surrender, Decline}; !reflect.DeepEqual(got, want) {
+			t.Errorf("offers %v, want %v", got, want)
+		}
+		do(t, g, Surrender)
+
+		h := g.Seats[0].Hand()
+		if g.Phase() != Settled || h.Outcome != Surrendered || g.Seats[0].Balance != 95 {
+			t.Errorf("phase %v, outcome %v, balance %d; want half the bet back", g.Phase(), h.Outcome, g.Seats[0].Balance)
+		}
+	})
+}
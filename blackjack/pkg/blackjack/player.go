@@ -0,0 +1,129 @@
+package blackjack
+
+import "fmt"
+
+// Player is a computer player: it places bets and picks actions, for
+// simulations and bots at the table.
+type Player interface {
+	// Bet returns the bet for the next round given the table's base bet
+	// and how many decks are left in the shoe.
+	Bet(base int, decksLeft float64) int
+	// Play picks one of g.Available().
+	Play(g *Game) Action
+	// See shows the player what happened at the table.
+	See(events []Event)
+}
+
+// Players are the names NewPlayer knows.
+var Players = []string{"basic", "mimic", "never-bust", "count"}
+
+// NewPlayer returns a player by name. spread is the most base bets a
+// counting player will bet.
+func NewPlayer(name string, rules Rules, spread int) (Player, error) {
+	switch name {
+	case "basic":
+		return &BasicPlayer{Strategy: NewStrategy(rules)}, nil
+	case "mimic":
+		return &MimicPlayer{HitSoft17: rules.HitSoft17}, nil
+	case "never-bust":
+		return NeverBustPlayer{}, nil
+	case "count":
+		return &CountingPlayer{Strategy: NewStrategy(rules), Count: NewCount(HiLo, rules.Decks), Spread: spread}, nil
+	}
+	return nil, fmt.Errorf("unknown player strategy %q, want one of %v", name, Players)
+}
+
+// BasicPlayer flat bets and plays basic strategy.
+type BasicPlayer struct {
+	Strategy *Strategy
+}
+
+func (p *BasicPlayer) Bet(base int, decksLeft float64) int {
+	return base
+}
+
+func (p *BasicPlayer) Play(g *Game) Action {
+	best, _ := p.Strategy.Best(g)
+	return best
+}
+
+func (p *BasicPlayer) See(events []Event) {}
+
+// MimicPlayer flat bets and plays like the dealer: it hits below 17 and
+// never doubles, splits, surrenders or takes insurance.
+type MimicPlayer struct {
+	HitSoft17 bool
+}
+
+func (p *MimicPlayer) Bet(base int, decksLeft float64) int {
+	return base
+}
+
+func (p *MimicPlayer) Play(g *Game) Action {
+	if g.Phase() == Offer {
+		return Decline
+	}
+	cards := g.Hand().Cards
+	value := cards.Value()
+	if value < 17 || (value == 17 && p.HitSoft17 && cards.Soft()) {
+		return Hit
+	}
+	return Stand
+}
+
+func (p *MimicPlayer) See(events []Event) {}
+
+// NeverBustPlayer flat bets and only hits when no card can bust the hand.
+type NeverBustPlayer struct{}
+
+func (NeverBustPlayer) Bet(base int, decksLeft float64) int {
+	return base
+}
+
+func (NeverBustPlayer) Play(g *Game) Action {
+	if g.Phase() == Offer {
+		return Decline
+	}
+	cards := g.Hand().Cards
+	if cards.Value() <= 11 || (cards.Soft() && cards.Value() < 18) {
+		return Hit
+	}
+	return Stand
+}
+
+func (NeverBustPlayer) See(events []Event) {}
+
+// CountingPlayer keeps a count, raises its bet with the true count up to
+// Spread base bets and plays basic strategy, taking insurance at a true
+// count of 3 or more.
+type CountingPlayer struct {
+	Strategy *Strategy
+	Count    Count
+	Spread   int
+}
+
+func (p *CountingPlayer) Bet(base int, decksLeft float64) int {
+	units := int(p.Count.True(decksLeft)) - 1
+	units = max(1, min(units, p.Spread))
+	return base * units
+}
+
+func (p *CountingPlayer) Play(g *Game) Action {
+	if g.Phase() == Offer && g.check(Insurance) == nil && p.Count.True(p.decksLeft(g)) >= 3 {
+		return Insurance
+	}
+	best, _ := p.Strategy.Best(g)
+	return best
+}
+
+// decksLeft is the decks left in the game's shoe, or one if it has none.
+func (p *CountingPlayer) decksLeft(g *Game) float64 {
+	if shoe, ok := g.source.(*Shoe); ok {
+		return shoe.DecksLeft()
+	}
+	return 1
+}
+
+func (p *CountingPlayer) See(events []Event) {
+	p.Count.See(events)
+}
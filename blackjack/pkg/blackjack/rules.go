@@ -0,0 +1,203 @@
+package blackjack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Rules are the table rules. The JSON form is what LoadRules reads, like
+//
+//	{"decks": 6, "hit_soft_17": true, "blackjack_pays": "3:2", "double": "9-11"}
+type Rules struct {
+	// Decks is how many 52 card decks are shuffled together.
+	Decks int `json:"decks"`
+	// HitSoft17 makes the dealer hit a soft 17 instead of standing.
+	HitSoft17 bool `json:"hit_soft_17"`
+	// BlackjackPays is what a natural wins, like 3:2.
+	BlackjackPays Payout `json:"blackjack_pays"`
+	// Double limits the totals that can be doubled.
+	Double DoubleRule `json:"double"`
+	// DoubleAfterSplit allows doubling hands made by splitting.
+	DoubleAfterSplit bool `json:"double_after_split"`
+	// MaxHands is how many hands splitting and re-splitting can make.
+	MaxHands int `json:"max_hands"`
+	// Surrender is the kind of surrender offered, if any.
+	Surrender SurrenderRule `json:"surrender"`
+	// Penetration is the share of the cards dealt before reshuffling.
+	Penetration float64 `json:"penetration"`
+}
+
+// DefaultRules are the rules of the original game: one deck, the dealer
+// stands on all 17s, a natural pays 1:1 and there is no surrender.
+func DefaultRules() Rules {
+	return Rules{
+		Decks:            1,
+		BlackjackPays:    Payout{1, 1},
+		Double:           DoubleAny,
+		DoubleAfterSplit: true,
+		MaxHands:         4,
+		Surrender:        NoSurrender,
+		Penetration:      0.8,
+	}
+}
+
+// LoadRules reads rules from a JSON file. Settings the file leaves out
+// keep their default.
+func LoadRules(path string) (Rules, error) {
+	rules := DefaultRules()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rules); err != nil {
+		return rules, fmt.Errorf("%v: %w", path, err)
+	}
+	if err := rules.Validate(); err != nil {
+		return rules, fmt.Errorf("%v: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Validate reports rules that cannot be played.
+func (r Rules) Validate() error {
+	switch {
+	case r.Decks < 1 || r.Decks > 8:
+		return fmt.Errorf("decks must be between 1 and 8, not %d", r.Decks)
+	case r.BlackjackPays.Num <= 0 || r.BlackjackPays.Den <= 0:
+		return fmt.Errorf("blackjack payout %v is not a positive ratio", r.BlackjackPays)
+	case r.Double != DoubleAny && r.Double != Double9to11 && r.Double != Double10to11:
+		return fmt.Errorf("double rule %q is not any, 9-11 or 10-11", r.Double)
+	case r.MaxHands < 1:
+		return fmt.Errorf("max hands must be at least 1, not %d", r.MaxHands)
+	case r.Surrender != NoSurrender && !r.Surrender.Offered():
+		return fmt.Errorf("surrender rule %q is not none, late or early", r.Surrender)
+	case r.Penetration <= 0 || r.Penetration > 1:
+		return fmt.Errorf("penetration must be more than 0 and at most 1, not %v", r.Penetration)
+	}
+	return nil
+}
+
+func (r Rules) String() string {
+	deck := "1 deck"
+	if r.Decks > 1 {
+		deck = fmt.Sprintf("%d decks", r.Decks)
+	}
+	dealer := "dealer stands on all 17s"
+	if r.HitSoft17 {
+		dealer = "dealer hits soft 17"
+	}
+	das := "no double after split"
+	if r.DoubleAfterSplit {
+		das = "double after split"
+	}
+	surrender := "no surrender"
+	if r.Surrender.Offered() {
+		surrender = string(r.Surrender) + " surrender"
+	}
+	return fmt.Sprintf("%v, %v, blackjack pays %v, double on %v, %v, up to %d hands, %v",
+		deck, dealer, r.BlackjackPays, r.Double, das, r.MaxHands, surrender)
+}
+
+// Payout is a win ratio like 3:2.
+type Payout struct {
+	Num, Den int
+}
+
+// Of returns the winnings for bet, not counting the stake.
+func (p Payout) Of(bet int) int {
+	return bet * p.Num / p.Den
+}
+
+func (p Payout) String() string {
+	return fmt.Sprintf("%d:%d", p.Num, p.Den)
+}
+
+func (p Payout) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Payout) UnmarshalText(text []byte) error {
+	num, den, ok := strings.Cut(string(text), ":")
+	n, err1 := strconv.Atoi(num)
+	d, err2 := strconv.Atoi(den)
+	if !ok || err1 != nil || err2 != nil || n <= 0 || d <= 0 {
+		return fmt.Errorf("payout %q is not a ratio like 3:2", text)
+	}
+	*p = Payout{n, d}
+	return nil
+}
+
+// DoubleRule limits the hands that can be doubled.
+type DoubleRule string
+
+const (
+	// DoubleAny allows doubling any first two cards.
+	DoubleAny DoubleRule = "any"
+	// Double9to11 allows doubling totals of 9, 10 and 11.
+	Double9to11 DoubleRule = "9-11"
+	// Double10to11 allows doubling totals of 10 and 11.
+	Double10to11 DoubleRule = "10-11"
+)
+
+// Allows reports whether a hand totalling value can be doubled.
+func (d DoubleRule) Allows(value int) bool {
+	switch d {
+	case DoubleAny:
+		return true
+	case Double9to11:
+		return value >= 9 && value <= 11
+	case Double10to11:
+		return value >= 10 && value <= 11
+	}
+	return false
+}
+
+func (d DoubleRule) MarshalText() ([]byte, error) {
+	return []byte(d), nil
+}
+
+func (d *DoubleRule) UnmarshalText(text []byte) error {
+	switch r := DoubleRule(text); r {
+	case DoubleAny, Double9to11, Double10to11:
+		*d = r
+		return nil
+	}
+	return fmt.Errorf("double rule %q is not any, 9-11 or 10-11", text)
+}
+
+// SurrenderRule is the kind of surrender offered.
+type SurrenderRule string
+
+const (
+	NoSurrender SurrenderRule = "none"
+	// LateSurrender is offered once the dealer has peeked, so never
+	// against a dealer blackjack.
+	LateSurrender SurrenderRule = "late"
+	// EarlySurrender gets half the bet back even against a dealer
+	// blackjack.
+	EarlySurrender SurrenderRule = "early"
+)
+
+// Offered reports whether the rule offers surrender at all.
+func (s SurrenderRule) Offered() bool {
+	return s == LateSurrender || s == EarlySurrender
+}
+
+func (s SurrenderRule) MarshalText() ([]byte, error) {
+	return []byte(s), nil
+}
+
+func (s *SurrenderRule) UnmarshalText(text []byte) error {
+	switch r := SurrenderRule(text); r {
+	case NoSurrender, LateSurrender, EarlySurrender:
+		*s = r
+		return nil
+	}
+	return fmt.Errorf("surrender rule %q is not none, late or early", text)
+}
@@ -0,0 +1,83 @@
+package blackjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  func(*Rules)
+		valid bool
+	}{
+		{"default", func(r *Rules) {}, true},
+		{"late surrender", func(r *Rules) { r.Surrender = LateSurrender }, true},
+		{"early surrender", func(r *Rules) { r.Surrender = EarlySurrender }, true},
+		{"empty surrender", func(r *Rules) { r.Surrender = "" }, false},
+		{"unknown surrender", func(r *Rules) { r.Surrender = "always" }, false},
+		{"double 10-11", func(r *Rules) { r.Double = Double10to11 }, true},
+		{"empty double", func(r *Rules) { r.Double = "" }, false},
+		{"no decks", func(r *Rules) { r.Decks = 0 }, false},
+		{"no payout", func(r *Rules) { r.BlackjackPays = Payout{} }, false},
+		{"no hands", func(r *Rules) { r.MaxHands = 0 }, false},
+		{"no penetration", func(r *Rules) { r.Penetration = 0 }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := DefaultRules()
+			tt.edit(&rules)
+			if err := rules.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want func(Rules) bool
+	}{
+		{"left out settings keep their default", `{"decks": 6, "blackjack_pays": "6:5"}`, func(r Rules) bool {
+			return r.Decks == 6 && r.BlackjackPays == Payout{6, 5} && r.Surrender == NoSurrender && r.Double == DoubleAny
+		}},
+		{"late surrender", `{"surrender": "late"}`, func(r Rules) bool {
+			return r.Surrender == LateSurrender
+		}},
+		{"empty surrender", `{"surrender": ""}`, nil},
+		{"empty double", `{"double": ""}`, nil},
+		{"unknown setting", `{"dealer_peeks": false}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			if err := os.WriteFile(path, []byte(tt.json), 0644); err != nil {
+				t.Fatal(err)
+			}
+			rules, err := LoadRules(path)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("LoadRules(%v) = %v, want an error", tt.json, rules)
+				}
+				return
+			}
+			if err != nil || !tt.want(rules) {
+				t.Errorf("LoadRules(%v) = %v, %v", tt.json, rules, err)
+			}
+		})
+	}
+}
+
+func TestEmptyRulesRefuse(t *testing.T) {
+	rules := DefaultRules()
+	rules.Surrender = ""
+	rules.Double = ""
+	g := NewGame(rules, stacked("10", "6", "6", "10"), 100)
+	deal(t, g, 10)
+	if got := g.Available(); len(got) != 2 {
+		t.Errorf("Available() = %v, want only hit and stand", got)
+	}
+}
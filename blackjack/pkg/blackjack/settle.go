@@ -0,0 +1,82 @@
+package blackjack
+
+import "fmt"
+
+// Outcome is how a hand ended.
+type Outcome int
+
+const (
+	Pending Outcome = iota
+	Win
+	// Blackjack is a natural beating the dealer, paid at the table's rate.
+	Blackjack
+	Push
+	Lose
+	Bust
+	Surrendered
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Win:
+		return "win"
+	case Blackjack:
+		return "blackjack"
+	case Push:
+		return "push"
+	case Lose:
+		return "lose"
+	case Bust:
+		return "bust"
+	case Surrendered:
+		return "surrender"
+	}
+	return "pending"
+}
+
+func (o Outcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *Outcome) UnmarshalText(text []byte) error {
+	for _, r := range []Outcome{Pending, Win, Blackjack, Push, Lose, Bust, Surrendered} {
+		if r.String() == string(text) {
+			*o = r
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown outcome %q", text)
+}
+
+// Settle compares a finished hand with the dealer's under rules and
+// returns the outcome and how much is paid back to the player, stake
+// included: twice the bet for a win, the bet plus the blackjack payout for
+// a natural, the bet for a push, half of it for a surrender and nothing
+// for a loss. A natural beats any other 21 and pushes with the dealer's;
+// even money is a win whatever the dealer has.
+func Settle(hand PlayerHand, dealer Hand, rules Rules) (Outcome, int) {
+	playerValue, dealerValue := hand.Cards.Value(), dealer.Value()
+	natural := hand.Cards.IsBlackjack() && !hand.Split
+	switch {
+	case hand.EvenMoney:
+		return Win, hand.Bet * 2
+	case hand.Surrendered:
+		if dealer.IsBlackjack() && rules.Surrender != EarlySurrender {
+			return Lose, 0
+		}
+		return Surrendered, hand.Bet / 2
+	case playerValue > 21:
+		return Bust, 0
+	case natural && dealer.IsBlackjack():
+		return Push, hand.Bet
+	case natural:
+		return Blackjack, hand.Bet + rules.BlackjackPays.Of(hand.Bet)
+	case dealer.IsBlackjack():
+		return Lose, 0
+	case dealerValue > 21 || playerValue > dealerValue:
+		return Win, hand.Bet * 2
+	case playerValue == dealerValue:
+		return Push, hand.Bet
+	}
+	return Lose, 0
+}
@@ -0,0 +1,62 @@
+package blackjack
+
+import "testing"
+
+// hand makes a hand of spades from card values like "A" and "10".
+func hand(values ...string) Hand {
+	h := make(Hand, len(values))
+	for i, v := range values {
+		h[i] = Card{Suit: "Spades", Value: v}
+	}
+	return h
+}
+
+func TestSettle(t *testing.T) {
+	threeTwo := DefaultRules()
+	threeTwo.BlackjackPays = Payout{3, 2}
+	sixFive := DefaultRules()
+	sixFive.BlackjackPays = Payout{6, 5}
+	late := DefaultRules()
+	late.Surrender = LateSurrender
+	early := DefaultRules()
+	early.Surrender = EarlySurrender
+
+	tests := []struct {
+		name    string
+		hand    PlayerHand
+		dealer  Hand
+		rules   Rules
+		outcome Outcome
+		payout  int
+	}{
+		{"natural pays 3:2", PlayerHand{Cards: hand("A", "K"), Bet: 10}, hand("10", "7"), threeTwo, Blackjack, 25},
+		{"natural pays 6:5", PlayerHand{Cards: hand("A", "K"), Bet: 10}, hand("10", "7"), sixFive, Blackjack, 22},
+		{"natural pays 1:1", PlayerHand{Cards: hand("A", "K"), Bet: 10}, hand("10", "7"), DefaultRules(), Blackjack, 20},
+		{"natural beats three card 21", PlayerHand{Cards: hand("A", "K"), Bet: 10}, hand("10", "5", "6"), threeTwo, Blackjack, 25},
+		{"natural pushes dealer blackjack", PlayerHand{Cards: hand("A", "K"), Bet: 10}, hand("A", "Q"), threeTwo, Push, 10},
+		{"split 21 is a plain win", PlayerHand{Cards: hand("A", "K"), Bet: 10, Split: true}, hand("10", "7"), threeTwo, Win, 20},
+		{"split 21 pushes dealer 21", PlayerHand{Cards: hand("A", "K"), Bet: 10, Split: true}, hand("10", "5", "6"), threeTwo, Push, 10},
+		{"split 21 loses to dealer blackjack", PlayerHand{Cards: hand("A", "K"), Bet: 10, Split: true}, hand("A", "Q"), threeTwo, Lose, 0},
+		{"three card 21 loses to dealer blackjack", PlayerHand{Cards: hand("7", "7", "7"), Bet: 10}, hand("A", "Q"), threeTwo, Lose, 0},
+		{"even money against dealer blackjack", PlayerHand{Cards: hand("A", "K"), Bet: 10, EvenMoney: true}, hand("A", "Q"), threeTwo, Win, 20},
+		{"even money against no blackjack", PlayerHand{Cards: hand("A", "K"), Bet: 10, EvenMoney: true}, hand("A", "6"), threeTwo, Win, 20},
+		{"late surrender", PlayerHand{Cards: hand("10", "6"), Bet: 10, Surrendered: true}, hand("10", "7"), late, Surrendered, 5},
+		{"late surrender against dealer blackjack", PlayerHand{Cards: hand("10", "6"), Bet: 10, Surrendered: true}, hand("A", "K"), late, Lose, 0},
+		{"early surrender against dealer blackjack", PlayerHand{Cards: hand("10", "6"), Bet: 10, Surrendered: true}, hand("K", "A"), early, Surrendered, 5},
+		{"bust loses even if the dealer busts", PlayerHand{Cards: hand("10", "6", "K"), Bet: 10}, hand("10", "6", "9"), DefaultRules(), Bust, 0},
+		{"dealer bust", PlayerHand{Cards: hand("10", "2"), Bet: 10}, hand("10", "6", "9"), DefaultRules(), Win, 20},
+		{"higher total", PlayerHand{Cards: hand("10", "9"), Bet: 10}, hand("10", "8"), DefaultRules(), Win, 20},
+		{"lower total", PlayerHand{Cards: hand("10", "8"), Bet: 10}, hand("10", "9"), DefaultRules(), Lose, 0},
+		{"equal totals push", PlayerHand{Cards: hand("10", "Q"), Bet: 10}, hand("K", "J"), DefaultRules(), Push, 10},
+		{"soft total", PlayerHand{Cards: hand("A", "7"), Bet: 10}, hand("10", "7"), DefaultRules(), Win, 20},
+		{"doubled win pays the doubled bet", PlayerHand{Cards: hand("6", "5", "K"), Bet: 20, Doubled: true}, hand("10", "8"), DefaultRules(), Win, 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, payout := Settle(tt.hand, tt.dealer, tt.rules)
+			if outcome != tt.outcome || payout != tt.payout {
+				t.Errorf("Settle(%v, %v) = %v, %d; want %v, %d", tt.hand.Cards, tt.dealer, outcome, payout, tt.outcome, tt.payout)
+			}
+		})
+	}
+}
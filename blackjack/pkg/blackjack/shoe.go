@@ -0,0 +1,92 @@
+package blackjack
+
+import "math/rand"
+
+// Shoe holds one or more decks shuffled together with a cut card placed at
+// the table's penetration. Dealt cards come back through Discard and the
+// shoe is only shuffled between rounds, once the cut card is out.
+type Shoe struct {
+	// Cards are the cards left to deal, the next one first.
+	Cards []Card
+	// Discards are the cards played since the last shuffle.
+	Discards []Card
+
+	decks int
+	// cut is how many cards are left when the cut card comes out.
+	cut int
+	rng *rand.Rand
+}
+
+// NewShoe returns a shuffled shoe of decks decks with the cut card placed
+// after penetration, the share of the cards to deal, like 0.75.
+func NewShoe(decks int, penetration float64, rng *rand.Rand) *Shoe {
+	total := decks * 52
+	s := &Shoe{
+		Cards: newDecks(decks),
+		decks: decks,
+		cut:   total - int(penetration*float64(total)),
+		rng:   rng,
+	}
+	s.shuffle()
+	return s
+}
+
+// Size is the number of cards in a full shoe.
+func (s *Shoe) Size() int {
+	return s.decks * 52
+}
+
+// DecksLeft is how many decks are left to deal, for true counts.
+func (s *Shoe) DecksLeft() float64 {
+	return float64(len(s.Cards)) / 52
+}
+
+// CutCardOut reports whether the cut card has been reached.
+func (s *Shoe) CutCardOut() bool {
+	return len(s.Cards) <= s.cut
+}
+
+// Draw deals the next card. A shoe dealt out in the middle of a round,
+// which only happens with very deep penetration, is refilled from the
+// discards, so Draw never fails.
+func (s *Shoe) Draw() Card {
+	if len(s.Cards) == 0 {
+		s.Cards, s.Discards = s.Discards, nil
+		if len(s.Cards) == 0 {
+			// every card is on the table; open new decks
+			s.Cards = newDecks(s.decks)
+		}
+		s.shuffle()
+	}
+	card := s.Cards[0]
+	s.Cards = s.Cards[1:]
+	return card
+}
+
+// Discard puts played cards on the discard pile.
+func (s *Shoe) Discard(cards ...Card) {
+	s.Discards = append(s.Discards, cards...)
+}
+
+// Shuffle puts the discards back and shuffles the whole shoe. Cards still
+// on the table stay out, so call it between rounds.
+func (s *Shoe) Shuffle() {
+	s.Cards = append(s.Cards, s.Discards...)
+	s.Discards = nil
+	s.shuffle()
+}
+
+// ShuffleIfDue shuffles if the cut card is out.
+func (s *Shoe) ShuffleIfDue() bool {
+	if !s.CutCardOut() {
+		return false
+	}
+	s.Shuffle()
+	return true
+}
+
+func (s *Shoe) shuffle() {
+	s.rng.Shuffle(len(s.Cards), func(i, j int) {
+		s.Cards[i], s.Cards[j] = s.Cards[j], s.Cards[i]
+	})
+}
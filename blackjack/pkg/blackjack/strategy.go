@@ -0,0 +1,367 @@
+package blackjack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Strategy is basic strategy for a set of rules: the action with the best
+// expected value for each decision, worked out from the rules rather than
+// looked up in printed charts. It assumes an infinite deck, so every card
+// is equally likely on every draw and tens are four times as likely, and
+// that the dealer has already peeked for blackjack when showing an ace or
+// a ten.
+type Strategy struct {
+	Rules Rules
+
+	// dealer holds, by upcard 2-11, the chance of the dealer ending on
+	// 17, 18, 19, 20, 21 or busting.
+	dealer [12][6]float64
+	hits   map[handState]float64
+}
+
+// handState is a total and whether an ace in it counts as 11.
+type handState struct {
+	total, up int
+	soft      bool
+}
+
+// NewStrategy works out basic strategy for rules.
+func NewStrategy(rules Rules) *Strategy {
+	s := &Strategy{Rules: rules, hits: map[handState]float64{}}
+	memo := map[handState][6]float64{}
+	for up := 2; up <= 11; up++ {
+		total, soft := add(0, false, up)
+		// the hole card cannot make a blackjack once the dealer has peeked
+		var weight float64
+		for v := 2; v <= 11; v++ {
+			if up+v == 21 {
+				continue
+			}
+			weight += chance(v)
+		}
+		for v := 2; v <= 11; v++ {
+			if up+v == 21 {
+				continue
+			}
+			t, so := add(total, soft, v)
+			dist := s.dealerFrom(t, so, memo)
+			for i := range dist {
+				s.dealer[up][i] += chance(v) / weight * dist[i]
+			}
+		}
+	}
+	return s
+}
+
+// chance is the chance of drawing a card worth v, 11 for an ace.
+func chance(v int) float64 {
+	if v == 10 {
+		return 4.0 / 13
+	}
+	return 1.0 / 13
+}
+
+// add adds a card worth v to a hand, counting one ace as 11 where that
+// does not bust it.
+func add(total int, soft bool, v int) (int, bool) {
+	aces := 0
+	if soft {
+		aces++
+	}
+	if v == 11 {
+		aces++
+	}
+	total += v
+	for total > 21 && aces > 0 {
+		total -= 10
+		aces--
+	}
+	return total, aces > 0
+}
+
+func (s *Strategy) dealerFrom(total int, soft bool, memo map[handState][6]float64) [6]float64 {
+	var dist [6]float64
+	switch {
+	case total > 21:
+		dist[5] = 1
+		return dist
+	case total >= 17 && !(total == 17 && soft && s.Rules.HitSoft17):
+		dist[total-17] = 1
+		return dist
+	}
+	key := handState{total: total, soft: soft}
+	if d, ok := memo[key]; ok {
+		return d
+	}
+	for v := 2; v <= 11; v++ {
+		t, so := add(total, soft, v)
+		next := s.dealerFrom(t, so, memo)
+		for i := range dist {
+			dist[i] += chance(v) * next[i]
+		}
+	}
+	memo[key] = dist
+	return dist
+}
+
+// stand is the expected value of standing on total.
+func (s *Strategy) stand(total, up int) float64 {
+	if total > 21 {
+		return -1
+	}
+	d := s.dealer[up]
+	ev := d[5]
+	for i, p := range d[:5] {
+		switch dealer := 17 + i; {
+		case total > dealer:
+			ev += p
+		case total < dealer:
+			ev -= p
+		}
+	}
+	return ev
+}
+
+// hit is the expected value of hitting and then playing on as well as
+// possible with hits and a stand.
+func (s *Strategy) hit(total int, soft bool, up int) float64 {
+	key := handState{total, up, soft}
+	if ev, ok := s.hits[key]; ok {
+		return ev
+	}
+	var ev float64
+	for v := 2; v <= 11; v++ {
+		t, so := add(total, soft, v)
+		if t > 21 {
+			ev -= chance(v)
+			continue
+		}
+		ev += chance(v) * max(s.stand(t, up), s.hit(t, so, up))
+	}
+	s.hits[key] = ev
+	return ev
+}
+
+// double is the expected value of doubling, per unit of the original bet.
+func (s *Strategy) double(total int, soft bool, up int) float64 {
+	var ev float64
+	for v := 2; v <= 11; v++ {
+		t, _ := add(total, soft, v)
+		ev += chance(v) * s.stand(t, up)
+	}
+	return 2 * ev
+}
+
+// split is the expected value of splitting a pair of cards worth v, per
+// unit of the original bet. Re-splitting is left out of the estimate.
+func (s *Strategy) split(v, up int) float64 {
+	var ev float64
+	for c := 2; c <= 11; c++ {
+		t, so := add(v, v == 11, c)
+		if v == 11 {
+			// split aces get one card
+			ev += chance(c) * s.stand(t, up)
+			continue
+		}
+		best := max(s.stand(t, up), s.hit(t, so, up))
+		if s.Rules.DoubleAfterSplit && s.Rules.Double.Allows(t) {
+			best = max(best, s.double(t, so, up))
+		}
+		ev += chance(c) * best
+	}
+	return 2 * ev
+}
+
+// first returns the expected values of the actions on a first two card
+// hand after the peek. pair is the value of each card of a pair, or 0.
+func (s *Strategy) first(total int, soft bool, pair, up int) map[Action]float64 {
+	evs := map[Action]float64{
+		Stand: s.stand(total, up),
+		Hit:   s.hit(total, soft, up),
+	}
+	if s.Rules.Double.Allows(total) {
+		evs[Double] = s.double(total, soft, up)
+	}
+	if pair > 0 && s.Rules.MaxHands > 1 {
+		evs[Split] = s.split(pair, up)
+	}
+	if s.Rules.Surrender.Offered() {
+		evs[Surrender] = -0.5
+	}
+	return evs
+}
+
+// EVs returns the expected value of each action available in g, per unit
+// of the active hand's bet.
+func (s *Strategy) EVs(g *Game) map[Action]float64 {
+	evs := map[Action]float64{}
+	hand := g.Hand()
+	total, soft := hand.Cards.value()
+	up := g.Upcard().Points()
+	pair := 0
+	if hand.Cards.IsPair() {
+		pair = hand.Cards[0].Points()
+	}
+
+	if g.Phase() == Offer {
+		// the chance the dealer has blackjack, found by the peek
+		bj := chance(10)
+		if up == 10 {
+			bj = chance(11)
+		}
+		play := s.first(total, soft, pair, up)
+		if g.Seat().natural() {
+			play = map[Action]float64{Stand: float64(g.Rules.BlackjackPays.Num) / float64(g.Rules.BlackjackPays.Den)}
+		}
+		decline := (1 - bj) * best(play)
+		if !g.Seat().natural() {
+			decline -= bj
+		}
+		for _, a := range g.Available() {
+			switch a {
+			case Insurance:
+				evs[a] = decline + 0.5*(3*bj-1)
+			case EvenMoney:
+				evs[a] = 1
+			case Surrender:
+				evs[a] = -0.5
+			case Decline:
+				evs[a] = decline
+			}
+		}
+		return evs
+	}
+
+	for _, a := range g.Available() {
+		switch a {
+		case Hit:
+			evs[a] = s.hit(total, soft, up)
+		case Stand:
+			evs[a] = s.stand(total, up)
+		case Double:
+			evs[a] = s.double(total, soft, up)
+		case Split:
+			evs[a] = s.split(pair, up)
+		case Surrender:
+			evs[a] = -0.5
+		}
+	}
+	return evs
+}
+
+// Best returns the basic strategy action in g and the expected value of
+// each available action.
+func (s *Strategy) Best(g *Game) (Action, map[Action]float64) {
+	evs := s.EVs(g)
+	return bestAction(evs), evs
+}
+
+func best(evs map[Action]float64) float64 {
+	return evs[bestAction(evs)]
+}
+
+// bestAction picks the action with the highest expected value, breaking
+// ties in a fixed order so advice does not change between calls.
+func bestAction(evs map[Action]float64) Action {
+	order := []Action{Stand, Hit, Double, Split, Surrender, Decline, EvenMoney, Insurance}
+	var found Action
+	for _, a := range order {
+		if ev, ok := evs[a]; ok && (found == "" || ev > evs[found]) {
+			found = a
+		}
+	}
+	return found
+}
+
+// FormatEVs lists expected values best first, like "stand -0.15, hit -0.29".
+func FormatEVs(evs map[Action]float64) string {
+	actions := make([]Action, 0, len(evs))
+	for a := range evs {
+		actions = append(actions, a)
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		if evs[actions[i]] != evs[actions[j]] {
+			return evs[actions[i]] > evs[actions[j]]
+		}
+		return actions[i] < actions[j]
+	})
+	parts := make([]string, len(actions))
+	for i, a := range actions {
+		parts[i] = fmt.Sprintf("%v %+.3f", a, evs[a])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Chart prints the hard, soft and pair tables against each dealer upcard:
+// H hit, S stand, P split, Dh/Ds double or else hit/stand, Rh/Rs/Rp
+// surrender or else hit/stand/split.
+func (s *Strategy) Chart() string {
+	var b strings.Builder
+	header := func(title string) {
+		fmt.Fprintf(&b, "%-6s", title)
+		for up := 2; up <= 11; up++ {
+			fmt.Fprintf(&b, "%4v", upcardName(up))
+		}
+		b.WriteString("\n")
+	}
+	row := func(name string, cell func(up int) string) {
+		fmt.Fprintf(&b, "%-6s", name)
+		for up := 2; up <= 11; up++ {
+			fmt.Fprintf(&b, "%4v", cell(up))
+		}
+		b.WriteString("\n")
+	}
+
+	header("Hard")
+	for total := 5; total <= 20; total++ {
+		row(fmt.Sprint(total), func(up int) string {
+			return chartCode(s.first(total, false, 0, up))
+		})
+	}
+	b.WriteString("\n")
+	header("Soft")
+	for other := 2; other <= 9; other++ {
+		row(fmt.Sprintf("A,%d", other), func(up int) string {
+			return chartCode(s.first(11+other, true, 0, up))
+		})
+	}
+	b.WriteString("\n")
+	header("Pairs")
+	for v := 2; v <= 11; v++ {
+		total, soft := add(v, v == 11, v)
+		name := upcardName(v)
+		row(name+","+name, func(up int) string {
+			return chartCode(s.first(total, soft, v, up))
+		})
+	}
+	return b.String()
+}
+
+// chartCode is the chart entry for a first two card hand.
+func chartCode(evs map[Action]float64) string {
+	plain := "h"
+	if evs[Stand] >= evs[Hit] {
+		plain = "s"
+	}
+	switch bestAction(evs) {
+	case Split:
+		return "P"
+	case Double:
+		return "D" + plain
+	case Surrender:
+		if ev, ok := evs[Split]; ok && ev >= max(evs[Hit], evs[Stand]) {
+			return "Rp"
+		}
+		return "R" + plain
+	}
+	return strings.ToUpper(plain)
+}
+
+func upcardName(v int) string {
+	if v == 11 {
+		return "A"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,361 @@
+// Package history keeps player profiles: a bankroll that carries over from
+// one game to the next and a log of every hand played, with the
+// statistics and bankroll graph shown by `blackjack stats`.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"blackjack/pkg/blackjack"
+)
+
+// Stake is the balance a new profile starts with, and what a broke one
+// starts over with.
+const Stake = 1000
+
+// Hand is one settled hand in the log.
+type Hand struct {
+	Time time.Time `json:"time"`
+	// Round numbers the profile's rounds; split hands share one.
+	Round   int                `json:"round"`
+	Cards   []string           `json:"cards"`
+	Dealer  []string           `json:"dealer"`
+	Actions []blackjack.Action `json:"actions"`
+	// Bet is the final bet, doubling included. Insurance is logged with the
+	// first hand of the round.
+	Bet       int               `json:"bet"`
+	Insurance int               `json:"insurance,omitempty"`
+	Outcome   blackjack.Outcome `json:"outcome"`
+	Payout    int               `json:"payout"`
+	// Net is what the hand won or lost, insurance included.
+	Net int `json:"net"`
+	// Balance is the balance once the round was settled.
+	Balance int `json:"balance"`
+}
+
+// Profile is a player's bankroll and hand history.
+type Profile struct {
+	Name    string `json:"name"`
+	Balance int    `json:"balance"`
+	// Restakes counts the times the profile went broke and started over.
+	Restakes int `json:"restakes,omitempty"`
+	// RestakedAfter is the number of rounds played before each restake.
+	RestakedAfter []int  `json:"restaked_after,omitempty"`
+	Rounds        int    `json:"rounds"`
+	Hands         []Hand `json:"hands"`
+}
+
+// Log adds the hands seat played in a settled round to the history and
+// keeps the seat's balance.
+func (p *Profile) Log(g *blackjack.Game, seat int, at time.Time) {
+	s := g.Seats[seat]
+	if !s.Playing() {
+		return
+	}
+	p.Rounds++
+	dealer := cardNames(g.Dealer)
+	for i, hand := range s.Hands {
+		h := Hand{
+			Time:    at,
+			Round:   p.Rounds,
+			Cards:   cardNames(hand.Cards),
+			Dealer:  dealer,
+			Actions: hand.Actions,
+			Bet:     hand.Bet,
+			Outcome: hand.Outcome,
+			Payout:  hand.Payout,
+			Net:     hand.Payout - hand.Bet,
+			Balance: s.Balance,
+		}
+		if i == 0 && s.InsuranceBet > 0 {
+			h.Insurance = s.InsuranceBet
+			h.Net -= s.InsuranceBet
+			if g.Dealer.IsBlackjack() {
+				h.Net += s.InsuranceBet * 3
+			}
+		}
+		p.Hands = append(p.Hands, h)
+	}
+	p.Balance = s.Balance
+}
+
+func cardNames(cards blackjack.Hand) []string {
+	names := make([]string, len(cards))
+	for i, c := range cards {
+		names[i] = c.String()
+	}
+	return names
+}
+
+// Book is every saved profile.
+type Book struct {
+	Profiles []*Profile `json:"profiles"`
+}
+
+// DefaultFile is where profiles are saved unless told otherwise.
+func DefaultFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "blackjack-profiles.json"
+	}
+	return filepath.Join(dir, "blackjack", "profiles.json")
+}
+
+// Load reads the profiles file. A missing file is no profiles yet.
+func Load(path string) (*Book, error) {
+	b := &Book{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return b, nil
+}
+
+// Save writes the profiles file.
+func (b *Book) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Find returns the profile called name, or nil.
+func (b *Book) Find(name string) *Profile {
+	for _, p := range b.Profiles {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Open returns the profile called name, creating it with the Stake if it
+// is new. A broke profile is restaked, reported by restaked.
+func (b *Book) Open(name string) (p *Profile, restaked bool) {
+	p = b.Find(name)
+	if p == nil {
+		p = &Profile{Name: name, Balance: Stake}
+		b.Profiles = append(b.Profiles, p)
+	}
+	if p.Balance <= 0 {
+		p.Balance = Stake
+		p.Restakes++
+		p.RestakedAfter = append(p.RestakedAfter, p.Rounds)
+		restaked = true
+	}
+	return p, restaked
+}
+
+// Stats sums up a profile's hands.
+type Stats struct {
+	Hands, Won, Pushed, Lost int
+	Blackjacks, Busts        int
+	Doubles, Surrenders      int
+	Wagered, Net             int
+	// SplitHands counts the hands played after splitting.
+	SplitHands int
+	// BiggestWin and BiggestLoss are the hands with the highest and lowest
+	// Net.
+	BiggestWin, BiggestLoss Hand
+}
+
+// Stats works out the profile's statistics.
+func (p *Profile) Stats() Stats {
+	var s Stats
+	for i, h := range p.Hands {
+		s.Hands++
+		switch h.Outcome {
+		case blackjack.Win, blackjack.Blackjack:
+			s.Won++
+		case blackjack.Push:
+			s.Pushed++
+		default:
+			s.Lost++
+		}
+		switch h.Outcome {
+		case blackjack.Blackjack:
+			s.Blackjacks++
+		case blackjack.Bust:
+			s.Busts++
+		case blackjack.Surrendered:
+			s.Surrenders++
+		}
+		split := false
+		for _, a := range h.Actions {
+			switch a {
+			case blackjack.Double:
+				s.Doubles++
+			case blackjack.Split:
+				split = true
+			}
+		}
+		if split {
+			s.SplitHands++
+		}
+		s.Wagered += h.Bet + h.Insurance
+		s.Net += h.Net
+		if i == 0 || h.Net > s.BiggestWin.Net {
+			s.BiggestWin = h
+		}
+		if i == 0 || h.Net < s.BiggestLoss.Net {
+			s.BiggestLoss = h
+		}
+	}
+	return s
+}
+
+// WinRate is the share of hands won, pushes left out.
+func (s Stats) WinRate() float64 {
+	if s.Won+s.Lost == 0 {
+		return 0
+	}
+	return float64(s.Won) / float64(s.Won+s.Lost)
+}
+
+// Report describes the profile: its statistics and bankroll graph.
+func (p *Profile) Report(width, height int) string {
+	var b strings.Builder
+	s := p.Stats()
+	fmt.Fprintf(&b, "%v: balance $%d", p.Name, p.Balance)
+	if p.Restakes > 0 {
+		fmt.Fprintf(&b, " (restaked %d time(s) after going broke)", p.Restakes)
+	}
+	b.WriteString("\n")
+	if s.Hands == 0 {
+		b.WriteString("No hands played yet.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Hands:          %d in %d rounds\n", s.Hands, p.Rounds)
+	fmt.Fprintf(&b, "Won:            %d, pushed %d, lost %d (win rate %.1f%%)\n", s.Won, s.Pushed, s.Lost, 100*s.WinRate())
+	fmt.Fprintf(&b, "Net result:     %+d on $%d wagered\n", s.Net, s.Wagered)
+	fmt.Fprintf(&b, "Biggest win:    %+d %v\n", s.BiggestWin.Net, describe(s.BiggestWin))
+	fmt.Fprintf(&b, "Biggest loss:   %+d %v\n", s.BiggestLoss.Net, describe(s.BiggestLoss))
+	fmt.Fprintf(&b, "Blackjacks:     %d\n", s.Blackjacks)
+	fmt.Fprintf(&b, "Busts:          %d\n", s.Busts)
+	fmt.Fprintf(&b, "Doubles:        %d, split hands %d, surrenders %d\n", s.Doubles, s.SplitHands, s.Surrenders)
+	b.WriteString("\nBankroll:\n")
+	b.WriteString(p.Graph(width, height))
+	return b.String()
+}
+
+// describe names a hand for the biggest win and loss.
+func describe(h Hand) string {
+	return fmt.Sprintf("on %v: %v against %v",
+		h.Time.Format("2006-01-02 15:04"), strings.Join(h.Cards, ", "), strings.Join(h.Dealer, ", "))
+}
+
+// point is a balance on the graph.
+type point struct {
+	balance int
+	// restake is the balance going back to the stake after going broke.
+	restake bool
+}
+
+// balances is the balance after each round, starting from the stake, with
+// a point of its own for each restake.
+func (p *Profile) balances() []point {
+	points := []point{{balance: Stake}}
+	restakes := p.RestakedAfter
+	round := 0
+	for i, h := range p.Hands {
+		// split hands share a round and its closing balance
+		if i+1 < len(p.Hands) && p.Hands[i+1].Round == h.Round {
+			continue
+		}
+		round = h.Round
+		for len(restakes) > 0 && restakes[0] < round {
+			points = append(points, point{Stake, true})
+			restakes = restakes[1:]
+		}
+		points = append(points, point{balance: h.Balance})
+	}
+	for range restakes {
+		points = append(points, point{Stake, true})
+	}
+	return points
+}
+
+// Graph draws the balance after each round as an ASCII chart of width
+// columns by height rows, with restakes drawn as R. With more points than
+// columns each column shows the balance at the end of its share of them,
+// and an R if there was a restake in it.
+func (p *Profile) Graph(width, height int) string {
+	points := p.balances()
+	if width < 1 || height < 2 {
+		return ""
+	}
+	if len(points) > width {
+		sampled := make([]point, width)
+		from := 0
+		for i := range sampled {
+			to := (i + 1) * (len(points) - 1) / width
+			sampled[i] = points[to]
+			for _, pt := range points[from : to+1] {
+				sampled[i].restake = sampled[i].restake || pt.restake
+			}
+			from = to + 1
+		}
+		sampled[0].balance = points[0].balance
+		points = sampled
+	}
+	low, high := points[0].balance, points[0].balance
+	restaked := false
+	for _, pt := range points {
+		low, high = min(low, pt.balance), max(high, pt.balance)
+		restaked = restaked || pt.restake
+	}
+	if low == high {
+		high++
+	}
+	row := func(v int) int {
+		return int(math.Round(float64(v-low) / float64(high-low) * float64(height-1)))
+	}
+
+	grid := make([][]byte, height)
+	for r := range grid {
+		grid[r] = []byte(strings.Repeat(" ", len(points)))
+	}
+	for c, pt := range points {
+		mark := byte('*')
+		if pt.restake {
+			mark = 'R'
+		}
+		grid[height-1-row(pt.balance)][c] = mark
+	}
+	labelWidth := max(len(fmt.Sprint(high)), len(fmt.Sprint(low)))
+	var b strings.Builder
+	for r, line := range grid {
+		label := ""
+		switch r {
+		case 0:
+			label = fmt.Sprint(high)
+		case height - 1:
+			label = fmt.Sprint(low)
+		}
+		fmt.Fprintf(&b, "%*s |%s\n", labelWidth, label, strings.TrimRight(string(line), " "))
+	}
+	fmt.Fprintf(&b, "%*s +%s\n", labelWidth, "", strings.Repeat("-", len(points)))
+	last := fmt.Sprint(p.Rounds)
+	fmt.Fprintf(&b, "%*s  round 0%*s\n", labelWidth, "", max(len(last)+1, len(points)-len("round 0")), last)
+	if restaked {
+		fmt.Fprintf(&b, "R: went broke and restaked with $%d\n", Stake)
+	}
+	return b.String()
+}
@@ -0,0 +1,205 @@
+// Package sim plays blackjack headlessly with a computer player to
+// measure the house edge of a strategy under a set of rules.
+package sim
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"blackjack/pkg/blackjack"
+)
+
+// base is the chip value of one base bet, large enough that 3:2, 6:5 and
+// half bet insurance pay whole chips.
+const base = 100
+
+// Config is a simulation.
+type Config struct {
+	Rules  blackjack.Rules
+	Player string
+	// Spread is the most base bets a counting player bets.
+	Spread int
+	Rounds int
+	// Workers play their share of the rounds in parallel. Worker i seeds
+	// its shoe with Seed+i, so a seed and worker count give the same
+	// results every run. Run uses at least one and at most Rounds.
+	Workers int
+	Seed    int64
+	// Bankroll in base bets, for the risk of ruin.
+	Bankroll float64
+}
+
+// Result sums up the rounds played, in base bets.
+type Result struct {
+	Config  Config
+	Rounds  int
+	Wagered float64
+	Net     float64
+	// SumSquares is the sum of the squared net result of each round.
+	SumSquares float64
+	Naturals   int
+	Busts      int
+}
+
+func (r *Result) add(o Result) {
+	r.Rounds += o.Rounds
+	r.Wagered += o.Wagered
+	r.Net += o.Net
+	r.SumSquares += o.SumSquares
+	r.Naturals += o.Naturals
+	r.Busts += o.Busts
+}
+
+// Run plays the simulation. A bet or action the game refuses stops it
+// with an error.
+func Run(c Config) (Result, error) {
+	if c.Rounds < 1 {
+		return Result{}, fmt.Errorf("rounds must be at least 1, not %d", c.Rounds)
+	}
+	// a worker without a round to play would only pad the report
+	c.Workers = min(max(c.Workers, 1), c.Rounds)
+	if err := c.Rules.Validate(); err != nil {
+		return Result{}, err
+	}
+	players := make([]blackjack.Player, c.Workers)
+	for i := range players {
+		p, err := blackjack.NewPlayer(c.Player, c.Rules, c.Spread)
+		if err != nil {
+			return Result{}, err
+		}
+		players[i] = p
+	}
+
+	results := make([]Result, c.Workers)
+	errs := make([]error, c.Workers)
+	var wg sync.WaitGroup
+	for i := 0; i < c.Workers; i++ {
+		rounds := c.Rounds / c.Workers
+		if i < c.Rounds%c.Workers {
+			rounds++
+		}
+		wg.Add(1)
+		go func(i, rounds int) {
+			defer wg.Done()
+			results[i], errs[i] = play(c.Rules, players[i], rounds, c.Seed+int64(i))
+		}(i, rounds)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	// summed in worker order so the floating point totals are repeatable
+	total := Result{Config: c}
+	for _, r := range results {
+		total.add(r)
+	}
+	return total, nil
+}
+
+// play plays rounds with one player and its own shoe. It stops at the
+// first bet or action the game refuses.
+func play(rules blackjack.Rules, p blackjack.Player, rounds int, seed int64) (Result, error) {
+	var r Result
+	shoe := blackjack.NewShoe(rules.Decks, rules.Penetration, rand.New(rand.NewSource(seed)))
+	game := blackjack.NewGame(rules, shoe, math.MaxInt64/2)
+	for n := 0; n < rounds; n++ {
+		// shuffle before betting so a counting player bets on a fresh count
+		if shoe.ShuffleIfDue() {
+			p.See([]blackjack.Event{{Kind: blackjack.Shuffled}})
+		}
+		bet := p.Bet(base, shoe.DecksLeft())
+		seat := game.Seats[0]
+		before := seat.Balance
+		if err := game.Deal(bet); err != nil {
+			return r, fmt.Errorf("round %d: player bet %d: %w", n+1, bet, err)
+		}
+		for game.Phase() != blackjack.Settled {
+			p.See(game.Events())
+			action := p.Play(game)
+			if err := game.Do(action); err != nil {
+				return r, fmt.Errorf("round %d: player chose %v, which the game refused: %w", n+1, action, err)
+			}
+		}
+		p.See(game.Events())
+
+		net := float64(seat.Balance-before) / base
+		r.Rounds++
+		r.Wagered += float64(bet) / base
+		r.Net += net
+		r.SumSquares += net * net
+		for _, hand := range seat.Hands {
+			switch hand.Outcome {
+			case blackjack.Blackjack:
+				r.Naturals++
+			case blackjack.Bust:
+				r.Busts++
+			}
+		}
+	}
+	return r, nil
+}
+
+// Mean is the average net result per round.
+func (r Result) Mean() float64 {
+	return r.Net / float64(r.Rounds)
+}
+
+// Variance is the variance of the net result per round.
+func (r Result) Variance() float64 {
+	mean := r.Mean()
+	return r.SumSquares/float64(r.Rounds) - mean*mean
+}
+
+// HouseEdge is what the house wins per unit wagered on the first bets; a
+// negative edge is a player advantage.
+func (r Result) HouseEdge() float64 {
+	return -r.Net / r.Wagered
+}
+
+// Interval is the half width of the 95% confidence interval of the house
+// edge.
+func (r Result) Interval() float64 {
+	perRound := 1.96 * math.Sqrt(r.Variance()/float64(r.Rounds))
+	return perRound * float64(r.Rounds) / r.Wagered
+}
+
+// RiskOfRuin is the chance of losing the whole bankroll playing on
+// forever, from the usual diffusion estimate exp(-2 mean bankroll /
+// variance). It is 1 for a losing game.
+func (r Result) RiskOfRuin() float64 {
+	mean := r.Mean()
+	if mean <= 0 {
+		return 1
+	}
+	return math.Exp(-2 * mean * r.Config.Bankroll / r.Variance())
+}
+
+// Report formats the result.
+func (r Result) Report() string {
+	var b strings.Builder
+	c := r.Config
+	fmt.Fprintf(&b, "Strategy:        %v", c.Player)
+	if c.Player == "count" {
+		fmt.Fprintf(&b, " (Hi-Lo, 1-%d spread)", c.Spread)
+	}
+	fmt.Fprintf(&b, "\nRules:           %v\n", c.Rules)
+	workers := fmt.Sprintf("%d workers", c.Workers)
+	if c.Workers == 1 {
+		workers = "1 worker"
+	}
+	fmt.Fprintf(&b, "Rounds:          %d (%v, seed %d)\n", r.Rounds, workers, c.Seed)
+	fmt.Fprintf(&b, "Wagered:         %.0f base bets\n", r.Wagered)
+	fmt.Fprintf(&b, "Net result:      %+.1f base bets\n", r.Net)
+	fmt.Fprintf(&b, "House edge:      %.3f%% ± %.3f%% (95%% confidence)\n", 100*r.HouseEdge(), 100*r.Interval())
+	fmt.Fprintf(&b, "Std deviation:   %.3f base bets per round (variance %.3f)\n", math.Sqrt(r.Variance()), r.Variance())
+	fmt.Fprintf(&b, "Blackjacks:      %.2f%% of rounds\n", 100*float64(r.Naturals)/float64(r.Rounds))
+	fmt.Fprintf(&b, "Busted hands:    %.2f%% of rounds\n", 100*float64(r.Busts)/float64(r.Rounds))
+	fmt.Fprintf(&b, "Risk of ruin:    %.2f%% with a bankroll of %.0f base bets\n", 100*r.RiskOfRuin(), c.Bankroll)
+	return b.String()
+}
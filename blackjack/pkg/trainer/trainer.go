@@ -0,0 +1,185 @@
+// Package trainer drills card counting: it deals cards from a shoe, checks
+// the running and true counts the player gives and keeps their progress
+// across sessions in a save file.
+package trainer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"blackjack/pkg/blackjack"
+)
+
+// Drill deals cards and keeps the count they should add up to.
+type Drill struct {
+	Shoe  *blackjack.Shoe
+	Count blackjack.Count
+}
+
+// NewDrill returns a drill on a fresh shoe.
+func NewDrill(system blackjack.System, decks int, rng *rand.Rand) *Drill {
+	return &Drill{
+		Shoe:  blackjack.NewShoe(decks, 0.75, rng),
+		Count: blackjack.NewCount(system, decks),
+	}
+}
+
+// Deal deals n cards and counts them. When the cut card is out, or fewer
+// than n cards are left, the shoe is shuffled and the count starts over
+// first, reported by shuffled.
+func (d *Drill) Deal(n int) (cards []blackjack.Card, shuffled bool) {
+	if d.Shoe.CutCardOut() || len(d.Shoe.Cards) < n {
+		d.Shoe.Shuffle()
+		d.Count.Reset()
+		shuffled = true
+	}
+	for i := 0; i < n; i++ {
+		card := d.Shoe.Draw()
+		d.Shoe.Discard(card)
+		d.Count.Add(card)
+		cards = append(cards, card)
+	}
+	return cards, shuffled
+}
+
+// DecksLeft is the decks left to deal, rounded to the half deck a player
+// would judge from the discard tray.
+func (d *Drill) DecksLeft() float64 {
+	return math.Max(0.5, math.Round(d.Shoe.DecksLeft()*2)/2)
+}
+
+// TrueCount is the running count divided by DecksLeft.
+func (d *Drill) TrueCount() float64 {
+	return float64(d.Count.Running) / d.DecksLeft()
+}
+
+// CheckTrue reports whether answer is the true count, truncated or
+// rounded, as both are used at the table.
+func (d *Drill) CheckTrue(answer int) bool {
+	tc := d.TrueCount()
+	return answer == int(tc) || answer == int(math.Round(tc))
+}
+
+// Session is the score of one training session.
+type Session struct {
+	Date      time.Time `json:"date"`
+	System    string    `json:"system"`
+	Decks     int       `json:"decks"`
+	Speed     float64   `json:"speed_seconds"`
+	Cards     int       `json:"cards"`
+	Questions int       `json:"questions"`
+	// RunningCorrect and TrueCorrect count right answers; TrueAsked is 0
+	// for unbalanced systems, which have no true count.
+	RunningCorrect int `json:"running_correct"`
+	TrueAsked      int `json:"true_asked"`
+	TrueCorrect    int `json:"true_correct"`
+	// AnswerSeconds is the total time spent answering.
+	AnswerSeconds float64 `json:"answer_seconds"`
+}
+
+// Accuracy is the share of all answers that were right.
+func (s Session) Accuracy() float64 {
+	asked := s.Questions + s.TrueAsked
+	if asked == 0 {
+		return 0
+	}
+	return float64(s.RunningCorrect+s.TrueCorrect) / float64(asked)
+}
+
+// AverageAnswer is the average time per answer.
+func (s Session) AverageAnswer() time.Duration {
+	asked := s.Questions + s.TrueAsked
+	if asked == 0 {
+		return 0
+	}
+	return time.Duration(s.AnswerSeconds / float64(asked) * float64(time.Second))
+}
+
+func (s Session) String() string {
+	line := fmt.Sprintf("%v  %-8v %d deck(s), %gs/card: running %d/%d",
+		s.Date.Format("2006-01-02 15:04"), s.System, s.Decks, s.Speed, s.RunningCorrect, s.Questions)
+	if s.TrueAsked > 0 {
+		line += fmt.Sprintf(", true %d/%d", s.TrueCorrect, s.TrueAsked)
+	}
+	return line + fmt.Sprintf(", %.0f%% right, %.1fs per answer", 100*s.Accuracy(), s.AverageAnswer().Seconds())
+}
+
+// Progress is every saved session.
+type Progress struct {
+	Sessions []Session `json:"sessions"`
+}
+
+// DefaultFile is where progress is saved unless told otherwise.
+func DefaultFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "blackjack-training.json"
+	}
+	return filepath.Join(dir, "blackjack", "training.json")
+}
+
+// Load reads the progress file. A missing file is no progress yet.
+func Load(path string) (*Progress, error) {
+	p := &Progress{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return p, nil
+}
+
+// Save writes the progress file.
+func (p *Progress) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Summary describes the progress with a system: the last few sessions and
+// the best accuracy and speed so far.
+func (p *Progress) Summary(system string) string {
+	var sessions []Session
+	for _, s := range p.Sessions {
+		if s.System == system {
+			sessions = append(sessions, s)
+		}
+	}
+	if len(sessions) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v progress over %d session(s):\n", system, len(sessions))
+	bestAccuracy, fastest := sessions[0], sessions[0]
+	for _, s := range sessions {
+		if s.Accuracy() > bestAccuracy.Accuracy() {
+			bestAccuracy = s
+		}
+		if s.AverageAnswer() < fastest.AverageAnswer() {
+			fastest = s
+		}
+	}
+	for _, s := range sessions[max(0, len(sessions)-5):] {
+		fmt.Fprintf(&b, "  %v\n", s)
+	}
+	fmt.Fprintf(&b, "Best accuracy: %.0f%% on %v\n", 100*bestAccuracy.Accuracy(), bestAccuracy.Date.Format("2006-01-02"))
+	fmt.Fprintf(&b, "Fastest answers: %.1fs on %v\n", fastest.AverageAnswer().Seconds(), fastest.Date.Format("2006-01-02"))
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+
+	"blackjack/pkg/blackjack"
+)
+
+// ruleFlags adds the table rule flags and -rules to fs. Call loadRules
+// with the -rules path once fs is parsed.
+func ruleFlags(fs *flag.FlagSet) (*blackjack.Rules, *string) {
+	rules := blackjack.DefaultRules()
+	path := fs.String("rules", "", "JSON file with the table rules; flags override it")
+	fs.IntVar(&rules.Decks, "decks", rules.Decks, "number of decks")
+	fs.BoolVar(&rules.HitSoft17, "h17", rules.HitSoft17, "dealer hits soft 17")
+	fs.TextVar(&rules.BlackjackPays, "blackjack-pays", rules.BlackjackPays, "what a natural pays, like 3:2 or 6:5")
+	fs.TextVar(&rules.Double, "double", rules.Double, "totals that can be doubled: any, 9-11 or 10-11")
+	fs.BoolVar(&rules.DoubleAfterSplit, "das", rules.DoubleAfterSplit, "allow doubling after a split")
+	fs.IntVar(&rules.MaxHands, "max-hands", rules.MaxHands, "how many hands splitting and re-splitting can make")
+	fs.TextVar(&rules.Surrender, "surrender", rules.Surrender, "surrender offered: none, late or early")
+	fs.Float64Var(&rules.Penetration, "penetration", rules.Penetration, "share of the cards dealt before reshuffling")
+	return &rules, path
+}
+
+// loadRules reads the rules file, if any, into rules and then applies the
+// rule flags given on the command line again so they win over the file.
+func loadRules(fs *flag.FlagSet, rules *blackjack.Rules, path string) error {
+	if path != "" {
+		set := map[string]string{}
+		fs.Visit(func(f *flag.Flag) {
+			set[f.Name] = f.Value.String()
+		})
+		loaded, err := blackjack.LoadRules(path)
+		if err != nil {
+			return err
+		}
+		*rules = loaded
+		for name, value := range set {
+			fs.Set(name, value)
+		}
+	}
+	return rules.Validate()
+}
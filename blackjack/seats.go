@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"blackjack/pkg/blackjack"
+	"blackjack/pkg/history"
+)
+
+// human is the seat name for a player at the keyboard.
+const human = "human"
+
+// seat is who sits at one of the game's seats.
+type seat struct {
+	name string
+	// bot plays the seat, or nil for a human.
+	bot blackjack.Player
+	// left is set once a human quits; the seat sits out from then on.
+	left bool
+	// profile keeps a human's bankroll and hands, if profiles are saved.
+	profile *history.Profile
+}
+
+// parseSeats reads the -seats list: "human" or a bot strategy for each
+// seat, in dealing order.
+func parseSeats(list string, rules blackjack.Rules, spread int) ([]*seat, error) {
+	var seats []*seat
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		s := &seat{name: name}
+		if name != human {
+			bot, err := blackjack.NewPlayer(name, rules, spread)
+			if err != nil {
+				return nil, err
+			}
+			s.bot = bot
+		}
+		seats = append(seats, s)
+	}
+	if len(seats) > blackjack.MaxSeats {
+		return nil, fmt.Errorf("the table has %d seats, not %d", blackjack.MaxSeats, len(seats))
+	}
+	return seats, nil
+}
+
+// openProfiles gives each human seat the profile named in names, in seat
+// order, and renames the seat after it. Humans past the end of names are
+// player2, player3 and so on.
+func openProfiles(seats []*seat, book *history.Book, names []string) error {
+	taken := map[string]bool{}
+	humans := 0
+	for _, s := range seats {
+		if s.bot != nil {
+			continue
+		}
+		humans++
+		name := fmt.Sprintf("player%d", humans)
+		if humans <= len(names) {
+			name = strings.TrimSpace(names[humans-1])
+		}
+		if name == "" || taken[name] {
+			return fmt.Errorf("each human seat needs its own profile name, not %q", name)
+		}
+		taken[name] = true
+		profile, restaked := book.Open(name)
+		if restaked {
+			fmt.Printf("%v went broke and starts over with $%d.\n", name, history.Stake)
+		}
+		s.name = name
+		s.profile = profile
+	}
+	return nil
+}
+
+// table is the game with the people in its seats.
+type table struct {
+	game  *blackjack.Game
+	seats []*seat
+}
+
+// humans reports whether any human is still at the table.
+func (t *table) humans() bool {
+	for _, s := range t.seats {
+		if s.bot == nil && !s.left {
+			return true
+		}
+	}
+	return false
+}
+
+// tell shows events to the bots, so counting bots see every card.
+func (t *table) tell(events []blackjack.Event) {
+	for _, s := range t.seats {
+		if s.bot != nil {
+			s.bot.See(events)
+		}
+	}
+}
+
+// seatLabel names seat i when there is more than one.
+func (t *table) seatLabel(i int) string {
+	if len(t.seats) > 1 {
+		return fmt.Sprintf("Seat %d (%s): ", i+1, t.seats[i].name)
+	}
+	return ""
+}
+
+// label names hand j of seat i when the table or the seat has more than
+// one.
+func (t *table) label(i, j int) string {
+	l := t.seatLabel(i)
+	if len(t.game.Seats[i].Hands) > 1 {
+		l += fmt.Sprintf("Hand %d: ", j+1)
+	}
+	return l
+}
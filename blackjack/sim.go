@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"blackjack/pkg/blackjack"
+	"blackjack/pkg/sim"
+)
+
+// runSim implements `blackjack sim`.
+func runSim(args []string) {
+	flags := flag.NewFlagSet("sim", flag.ExitOnError)
+	rules, rulesFile := ruleFlags(flags)
+	player := flags.String("strategy", "basic", "player strategy: "+strings.Join(blackjack.Players, ", "))
+	rounds := flags.Int("rounds", 1000000, "rounds to play")
+	workers := flags.Int("workers", runtime.NumCPU(), "goroutines playing in parallel")
+	seed := flags.Int64("seed", 1, "seed of the first worker's shoe; worker i uses seed+i")
+	spread := flags.Int("spread", 8, "most base bets the count strategy bets")
+	bankroll := flags.Float64("bankroll", 1000, "bankroll in base bets, for the risk of ruin")
+	flags.Parse(args)
+
+	if err := loadRules(flags, rules, *rulesFile); err != nil {
+		fmt.Println("Error loading rules:", err)
+		os.Exit(1)
+	}
+
+	start := time.Now()
+	result, err := sim.Run(sim.Config{
+		Rules:    *rules,
+		Player:   *player,
+		Spread:   *spread,
+		Rounds:   *rounds,
+		Workers:  *workers,
+		Seed:     *seed,
+		Bankroll: *bankroll,
+	})
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	fmt.Print(result.Report())
+	fmt.Printf("Time:            %v\n", time.Since(start).Round(time.Millisecond))
+}
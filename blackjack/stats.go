@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"blackjack/pkg/history"
+)
+
+// runStats implements `blackjack stats`.
+func runStats(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	profiles := flags.String("profiles", history.DefaultFile(), "file keeping the players' bankrolls and hands")
+	name := flags.String("name", "", "profile to show; all of them if empty")
+	width := flags.Int("width", 60, "columns of the bankroll graph")
+	height := flags.Int("height", 12, "rows of the bankroll graph")
+	flags.Parse(args)
+
+	book, err := history.Load(*profiles)
+	if err != nil {
+		fmt.Println("Error loading profiles:", err)
+		os.Exit(1)
+	}
+	shown := book.Profiles
+	if *name != "" {
+		p := book.Find(*name)
+		if p == nil {
+			fmt.Printf("Error: no profile called %q in %v\n", *name, *profiles)
+			os.Exit(1)
+		}
+		shown = []*history.Profile{p}
+	}
+	if len(shown) == 0 {
+		fmt.Println("No profiles yet: play a game first.")
+		return
+	}
+	for i, p := range shown {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Print(p.Report(*width, *height))
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"blackjack/pkg/blackjack"
+	"blackjack/pkg/trainer"
+)
+
+// runTrain implements `blackjack train`.
+func runTrain(args []string) {
+	flags := flag.NewFlagSet("train", flag.ExitOnError)
+	systemName := flags.String("system", "hi-lo", "counting system: hi-lo, ko or omega-ii")
+	decks := flags.Int("decks", 6, "number of decks in the shoe")
+	speed := flags.Duration("speed", time.Second, "how long each card is shown")
+	cards := flags.Int("cards", 10, "cards dealt between questions")
+	questions := flags.Int("questions", 10, "questions in the session")
+	saveFile := flags.String("save", trainer.DefaultFile(), "file keeping progress across sessions")
+	flags.Parse(args)
+
+	system, err := blackjack.SystemByName(*systemName)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(2)
+	}
+	if *decks < 1 || *decks > 8 || *cards < 1 || *cards > *decks*26 || *questions < 1 {
+		fmt.Println("Error: need 1-8 decks, at least one question and 1 to half a shoe of cards per question")
+		os.Exit(2)
+	}
+	progress, err := trainer.Load(*saveFile)
+	if err != nil {
+		fmt.Println("Error loading progress:", err)
+		os.Exit(1)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	drill := trainer.NewDrill(system, *decks, rand.New(rand.NewSource(time.Now().UnixNano())))
+	session := trainer.Session{
+		Date:      time.Now(),
+		System:    system.Name,
+		Decks:     *decks,
+		Speed:     speed.Seconds(),
+		Cards:     *cards,
+		Questions: *questions,
+	}
+
+	fmt.Printf("Counting trainer: %v with %d deck(s), %d cards at %v each between questions.\n", system.Name, *decks, *cards, *speed)
+	fmt.Printf("The count starts at %d.\n", drill.Count.Running)
+	for q := 1; q <= *questions; q++ {
+		fmt.Printf("\nQuestion %d/%d. Press Enter to deal.", q, *questions)
+		reader.ReadString('\n')
+		dealt, shuffled := drill.Deal(*cards)
+		if shuffled && q > 1 {
+			fmt.Printf("The cut card is out. Shuffling; the count starts over at %d.\n", system.Start(*decks))
+		}
+		for _, card := range dealt {
+			// each card overwrites the last so it cannot be read back
+			fmt.Printf("\r  %-20v", card)
+			time.Sleep(*speed)
+		}
+		fmt.Printf("\r%-22s\r", "")
+
+		answer, took := ask(reader, "Running count? ")
+		session.AnswerSeconds += took.Seconds()
+		if answer == drill.Count.Running {
+			session.RunningCorrect++
+			fmt.Println("Right.")
+		} else {
+			fmt.Printf("Wrong, the running count is %d.\n", drill.Count.Running)
+		}
+
+		if system.Balanced() {
+			answer, took := ask(reader, fmt.Sprintf("True count with %.1f deck(s) left? ", drill.DecksLeft()))
+			session.AnswerSeconds += took.Seconds()
+			session.TrueAsked++
+			if drill.CheckTrue(answer) {
+				session.TrueCorrect++
+				fmt.Println("Right.")
+			} else {
+				fmt.Printf("Wrong, the true count is %.1f.\n", drill.TrueCount())
+			}
+		}
+	}
+
+	fmt.Printf("\nSession: %v\n", session)
+	progress.Sessions = append(progress.Sessions, session)
+	if err := progress.Save(*saveFile); err != nil {
+		fmt.Println("Error saving progress:", err)
+		os.Exit(1)
+	}
+	fmt.Print(progress.Summary(system.Name))
+}
+
+// ask prompts until it reads a whole number and returns it with the time
+// taken to answer.
+func ask(reader *bufio.Reader, prompt string) (int, time.Duration) {
+	start := time.Now()
+	for {
+		fmt.Print(prompt)
+		input, err := reader.ReadString('\n')
+		n, convErr := strconv.Atoi(strings.TrimSpace(input))
+		if convErr == nil {
+			return n, time.Since(start)
+		}
+		if err != nil {
+			// input ended; count it as a wrong answer
+			fmt.Println()
+			return n, time.Since(start)
+		}
+		fmt.Println("Please enter a whole number.")
+	}
+}
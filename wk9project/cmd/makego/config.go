@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/config"
+)
+
+// runConfig implements makego config show, which prints the effective
+// configuration and where each setting came from.
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "show" {
+		fmt.Println("Usage: makego config show [-profile name]")
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("config show", flag.ExitOnError)
+	fs.String("profile", "", "config profile to show")
+	fs.Parse(args[1:])
+
+	conf, err := config.Load(config.Options{Flags: setFlags(fs)})
+	if err != nil {
+		fmt.Println("Error loading config:", err)
+		os.Exit(1)
+	}
+	fmt.Print(conf.Show())
+}
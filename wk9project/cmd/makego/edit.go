@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/config"
+	"github.com/jeremycruzz/msds301-wk9/pkg/history"
+	"github.com/jeremycruzz/msds301-wk9/pkg/llm"
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+)
+
+// runEdit implements `makego edit [-apikey KEY] [-git] <dir> <instruction>`.
+func runEdit(args []string) {
+	flags := flag.NewFlagSet("edit", flag.ExitOnError)
+	flags.String("apikey", "", "API key for chatgpt")
+	flags.String("profile", "", "config profile to use")
+	useGit := flags.Bool("git", false, "commit the edit so it can be undone with makego undo")
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		fmt.Println("Usage: makego edit [-apikey KEY] [-git] <dir> <instruction>")
+		os.Exit(2)
+	}
+	dir, instruction := flags.Arg(0), strings.Join(flags.Args()[1:], " ")
+
+	conf, err := config.Load(config.Options{Flags: setFlags(flags)})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if conf.APIKey == "" {
+		log.Fatal("API key is required. Start with -apikey flag.")
+	}
+	client, err := llm.New(llm.Config{Provider: conf.Provider, Model: conf.Model, Temperature: conf.Temperature}, conf.APIKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	gen := &project.Generator{Client: client, Log: os.Stdout, Git: *useGit}
+	if err := gen.Edit(dir, instruction); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
+
+// runUndo implements `makego undo <dir>`.
+func runUndo(args []string) {
+	flags := flag.NewFlagSet("undo", flag.ExitOnError)
+	flags.Parse(args)
+
+	dir := flags.Arg(0)
+	if dir == "" {
+		dir = "."
+	}
+	subject, err := history.Undo(dir)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Undid %q\n", subject)
+}
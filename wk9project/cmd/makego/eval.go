@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/config"
+	"github.com/jeremycruzz/msds301-wk9/pkg/eval"
+)
+
+// runEval implements `makego eval -apikey KEY [-out dir] <suite.json>`.
+func runEval(args []string) {
+	flags := flag.NewFlagSet("eval", flag.ExitOnError)
+	flags.String("apikey", "", "API key for the providers")
+	flags.String("profile", "", "config profile to use")
+	out := flags.String("out", "eval-results", "directory for the results; each evaluation gets its own timestamped folder in it")
+	parallel := flags.Int("parallel", 1, "number of generations to run at once")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Println("Usage: makego eval -apikey KEY [-out dir] [-parallel n] <suite.json>")
+		os.Exit(2)
+	}
+
+	conf, err := config.Load(config.Options{Flags: setFlags(flags)})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if conf.APIKey == "" {
+		log.Fatal("API key is required. Start with -apikey flag.")
+	}
+
+	suite, err := eval.Load(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	// a fresh folder per evaluation, so earlier projects never collide
+	// with this run's
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		log.Fatal(err)
+	}
+	dir, err := os.MkdirTemp(*out, time.Now().Format("20060102-150405-"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	root := filepath.Join(dir, "projects")
+	if err := os.Mkdir(root, 0755); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Writing results to", dir)
+
+	runs, err := eval.Evaluate(suite, eval.Options{APIKey: conf.APIKey, Root: root, Parallel: *parallel, Log: os.Stdout, Env: conf.SandboxEnv()})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	summaries := eval.Summarize(suite, runs)
+
+	data, err := json.MarshalIndent(struct {
+		Suite     string         `json:"suite"`
+		Summaries []eval.Summary `json:"summaries"`
+		Runs      []eval.Run     `json:"runs"`
+	}{suite.Name, summaries, runs}, "", "  ")
+	if err != nil {
+		fmt.Println("Error encoding results:", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(dir, "results.json"), append(data, '\n'), 0644); err != nil {
+		fmt.Println("Error writing results:", err)
+		return
+	}
+
+	table := eval.Markdown(suite, summaries)
+	if err := os.WriteFile(filepath.Join(dir, "results.md"), []byte(table), 0644); err != nil {
+		fmt.Println("Error writing results:", err)
+		return
+	}
+	fmt.Print("\n", table)
+}
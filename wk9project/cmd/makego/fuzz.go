@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/config"
+	"github.com/jeremycruzz/msds301-wk9/pkg/llm"
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+)
+
+// runFuzz implements `makego fuzz -apikey KEY [-fuzztime 30s] [-race] <dir>`.
+func runFuzz(args []string) {
+	flags := flag.NewFlagSet("fuzz", flag.ExitOnError)
+	flags.String("apikey", "", "API key for chatgpt")
+	flags.String("profile", "", "config profile to use")
+	fuzzTime := flags.Duration("fuzztime", 30*time.Second, "how long to run each fuzz target")
+	race := flags.Bool("race", false, "run the race detector as well")
+	flags.Parse(args)
+
+	dir := flags.Arg(0)
+	if dir == "" {
+		dir = "."
+	}
+	conf, err := config.Load(config.Options{Flags: setFlags(flags)})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if conf.APIKey == "" {
+		log.Fatal("API key is required. Start with -apikey flag.")
+	}
+	client, err := llm.New(llm.Config{Provider: conf.Provider, Model: conf.Model, Temperature: conf.Temperature}, conf.APIKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	gen := &project.Generator{Client: client, Log: os.Stdout, Env: conf.SandboxEnv()}
+	if *race {
+		gen.Race(dir)
+	}
+	gen.Fuzz(dir, *fuzzTime, *race)
+}
@@ -1,97 +1,329 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
 
-	"github.com/jeremycruzz/msds301-wk8/pkg/chatgpt"
+	"github.com/jeremycruzz/msds301-wk9/pkg/config"
+	"github.com/jeremycruzz/msds301-wk9/pkg/docs"
+	"github.com/jeremycruzz/msds301-wk9/pkg/history"
+	"github.com/jeremycruzz/msds301-wk9/pkg/llm"
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+	"github.com/jeremycruzz/msds301-wk9/pkg/retrieve"
+	"github.com/jeremycruzz/msds301-wk9/pkg/spec"
 )
 
 func main() {
-	var promptBuilder strings.Builder
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "review":
+			runReview(os.Args[2:])
+			return
+		case "fuzz":
+			runFuzz(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "package":
+			runPackage(os.Args[2:])
+			return
+		case "session":
+			runSession(os.Args[2:])
+			return
+		case "eval":
+			runEval(os.Args[2:])
+			return
+		case "edit":
+			runEdit(os.Args[2:])
+			return
+		case "refactor":
+			runRefactor(os.Args[2:])
+			return
+		case "record":
+			runRecord(os.Args[2:])
+			return
+		case "undo":
+			runUndo(os.Args[2:])
+			return
+		case "config":
+			runConfig(os.Args[2:])
+			return
+		}
+	}
+
 	defaultPrompt := "I need a program that analyzes all four sets of the AnscombeQuartet dataset using linear regression and prints 'Set I: m= b=' for each of the four sets."
 
-	// get from flags
-	apiKey := flag.String("apikey", "", "API key for chatgpt")
+	// get from flags, defaults come from the config files
+	defaults := config.Defaults()
+	flag.String("apikey", "", "API key for chatgpt")
 	name := flag.String("name", "newProject", "Name for go module")
 	prompt := flag.String("prompt", defaultPrompt, "prompt for chat gpt program")
+	race := flag.Bool("race", false, "build and test with the race detector")
+	fuzzTime := flag.Duration("fuzztime", 0, "ask for fuzz targets and run each for this long, 0 to skip")
+	specFile := flag.String("spec", "", "structured requirements file used instead of -prompt")
+	flag.String("provider", defaults.Provider, "model provider: chatgpt or openai")
+	flag.String("model", "", "model name for the openai provider")
+	flag.Float64("temperature", 0, "sampling temperature for the openai provider")
+	flag.String("template", defaults.Template, "prompt template: "+templateNames()+" or a template file")
+	flag.Int("repairs", 0, "how many times a failed build is sent back to the model")
+	flag.String("profile", "", "config profile to use")
+	flag.String("sandbox", defaults.Sandbox, "environment for running generated programs: none or clean-env")
+	flag.String("output", defaults.Output, "directory projects are generated in")
+	dryRun := flag.Bool("dry-run", false, "print the prompt, directory, commands and provider settings without doing anything")
+	plan := flag.Bool("plan", false, "ask for a design outline to approve before generating code")
+	writeDocs := flag.Bool("docs", false, "ask for a package doc comment and README and check the README's example commands")
+	useGit := flag.Bool("git", false, "commit every generation and repair attempt to git, in a new repository unless the output directory is in one")
+	goVersion := flag.String("go", "", "Go version for the go directive and the prompt, like 1.21; the build is verified with that release")
+	toolchain := flag.String("toolchain", "", "toolchain line for go.mod, like go1.21.5; the build is verified with it")
+	contextPaths := flag.String("context", "", "comma separated directories of existing Go packages to reuse")
+	contextBudget := flag.Int("context-budget", 1500, "maximum estimated tokens of -context declarations added to the prompt")
 
 	flag.Parse()
 
-	if *apiKey == "" {
+	conf, err := config.Load(config.Options{Flags: setFlags(flag.CommandLine)})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if conf.APIKey == "" && !*dryRun {
 		log.Fatal("API key is required. Start with -apikey flag.")
 	}
 
-	// string to only get code
-	promptBuilder.WriteString("I am going to ask you to write a go program for me all contained within a main.go file. Only respond with the contents of this main.go file in raw text and nothing else. I'm going to paste your response directly into a go file. Do not include anything before or after the code including comments explaining the code. ")
-	promptBuilder.WriteString(*prompt)
+	var s *spec.Spec
+	if *specFile != "" {
+		var err error
+		s, err = spec.Load(*specFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*prompt = s.Prompt()
+		if s.Name != "" && !isFlagSet("name") {
+			*name = s.Name
+		}
+	}
 
-	newDir := fmt.Sprintf("../%v", *name)
-	chatgpt := chatgpt.New(*apiKey)
+	// a toolchain older than the installed go needs a matching go directive
+	if *toolchain != "" && *goVersion == "" {
+		*goVersion = strings.TrimPrefix(*toolchain, "go")
+	}
 
-	// create new directory
-	fmt.Printf("Creating directory: %v...\n", newDir)
-	err := os.Mkdir(newDir, 0755)
+	tmpl, err := project.LoadTemplate(conf.Template)
 	if err != nil {
-		fmt.Println("Error creating directory:", err)
-		return
+		log.Fatal(err)
 	}
-
-	// cd new directory
-	err = os.Chdir(newDir)
+	cfg := llm.Config{Provider: conf.Provider, Model: conf.Model, Temperature: conf.Temperature}
+	client, err := llm.New(cfg, conf.APIKey)
 	if err != nil {
-		fmt.Println("Error changing directory:", err)
-		return
+		log.Fatal(err)
 	}
 
-	// init go mod
-	fmt.Printf("Creating go module: %v...\n", *name)
-	cmd := exec.Command("go", "mod", "init", *name)
-	err = cmd.Run()
-	if err != nil {
-		fmt.Println("Error initializing go module:", err)
+	opts := project.Options{
+		Name:      *name,
+		Prompt:    *prompt,
+		Root:      conf.Output,
+		Race:      *race,
+		FuzzTime:  *fuzzTime,
+		Template:  tmpl,
+		Repairs:   conf.Repairs,
+		GoVersion: *goVersion,
+		Toolchain: *toolchain,
+	}
+
+	if *contextPaths != "" {
+		opts.Context, opts.Replaces, err = loadContext(*contextPaths, opts.Prompt, *contextBudget)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *dryRun {
+		if conf.Profile != "" {
+			fmt.Printf("Profile: %v\n", conf.Profile)
+		}
+		fmt.Printf("Provider: %v (model %v, temperature %v)\n", cfg.Provider, orDefault(cfg.Model, "provider default"), cfg.Temperature)
+		fmt.Printf("Sandbox: %v\n", conf.Sandbox)
+		if *writeDocs {
+			fmt.Println("Docs: ask the model for a package doc comment and README.md, then run the README's example commands")
+		}
+		if *useGit {
+			fmt.Println("Git: commit every generation and repair attempt")
+		}
+		if *plan {
+			fmt.Println("Plan: ask the model for a design outline and wait for approval before generating code")
+		}
+		if err := project.DryRun(os.Stdout, opts); err != nil {
+			log.Fatal(err)
+		}
+		if s != nil {
+			fmt.Printf("  (check %d requirement(s) from %v)\n", len(s.Requirements), *specFile)
+		}
 		return
 	}
 
-	//ask chat gpt for code
-	fmt.Printf("Asking chatgpt: \n%v", promptBuilder.String())
-	code, err := chatgpt.AskCustom(promptBuilder.String())
+	gen := &project.Generator{Client: client, Log: os.Stdout, Git: *useGit, Env: conf.SandboxEnv()}
+	if *plan {
+		outline, ok := approveOutline(gen, opts.Prompt)
+		if !ok {
+			fmt.Println("Plan rejected, nothing generated.")
+			return
+		}
+		opts.Prompt = project.WithOutline(opts.Prompt, outline)
+	}
+
+	res, err := gen.Create(opts)
 	if err != nil {
-		fmt.Println("Error with chatgpt", err)
+		fmt.Println("Error:", err)
 		return
 	}
 
-	//remove ```go and ```
-	code = code[6 : len(code)-3]
+	if s != nil {
+		s.Env = conf.SandboxEnv()
+		checkSpec(res.Dir, s, client)
+	}
+	if *writeDocs {
+		document(res.Dir, opts.Name, client, conf.SandboxEnv(), *useGit)
+	}
+}
 
-	// write code
-	fmt.Println("Writing to main.go...")
-	err = os.WriteFile("main.go", []byte(code), 0644)
+// document writes the package doc comment and README, prints the README
+// command checks and commits the docs when using git.
+func document(dir, name string, client docs.Asker, env []string, useGit bool) {
+	commands, err := docs.Write(client, docs.Options{Dir: dir, Name: name, Env: env, Log: os.Stdout})
 	if err != nil {
-		fmt.Println("Error writing Go code to file:", err)
+		fmt.Println("Error writing docs:", err)
+		return
+	}
+	report := docs.Report(commands)
+	fmt.Print(report)
+	if !useGit {
 		return
 	}
+	if _, err := history.Commit(dir, "docs for "+name, "README commands:\n"+report); err != nil {
+		fmt.Println("Error committing docs:", err)
+	}
+}
+
+// checkSpec prints the requirements checklist and saves it as
+// requirements.json in the project.
+func checkSpec(dir string, s *spec.Spec, client spec.Asker) {
+	fmt.Println("Checking requirements...")
+	results, err := spec.Check(dir, s, client)
+	if err != nil {
+		fmt.Println("Error checking requirements:", err)
+		if results == nil {
+			return
+		}
+	}
+	fmt.Print(spec.Report(results))
 
-	// tidy module
-	fmt.Println("Tidying dependencies...")
-	cmd = exec.Command("go", "mod", "tidy")
-	err = cmd.Run()
+	data, err := json.MarshalIndent(results, "", "  ")
 	if err != nil {
-		fmt.Println("Error running go mod tidy:", err)
+		fmt.Println("Error encoding requirements:", err)
 		return
 	}
+	err = os.WriteFile(filepath.Join(dir, "requirements.json"), append(data, '\n'), 0644)
+	if err != nil {
+		fmt.Println("Error writing requirements:", err)
+	}
+}
 
-	// build
-	cmd = exec.Command("go", "build")
-	err = cmd.Run()
+// loadContext indexes the packages under paths and returns the
+// declarations relevant to prompt along with the modules they come from.
+func loadContext(paths, prompt string, budget int) (string, map[string]string, error) {
+	ix, err := retrieve.Load(strings.Split(paths, ","))
 	if err != nil {
-		fmt.Println("Error building the project:", err)
-		return
+		return "", nil, err
+	}
+	for _, skipped := range ix.Skipped {
+		fmt.Println("Context: skipping", skipped)
+	}
+	symbols := ix.Select(prompt, budget)
+	if len(symbols) == 0 {
+		fmt.Println("Context: nothing in", paths, "looks relevant to the prompt")
+		return "", nil, nil
+	}
+	replaces := map[string]string{}
+	for _, mod := range retrieve.Modules(symbols) {
+		replaces[mod.Path] = mod.Dir
+	}
+	context := retrieve.Prompt(symbols)
+	fmt.Printf("Context: %d declaration(s), about %d tokens\n", len(symbols), retrieve.Tokens(context))
+	return context, replaces, nil
+}
+
+// approveOutline shows design outlines until the user approves one with
+// y or rejects with n. Anything else is sent back as feedback.
+func approveOutline(gen *project.Generator, prompt string) (string, bool) {
+	reader := bufio.NewReader(os.Stdin)
+	outline, feedback := "", ""
+	for {
+		var err error
+		outline, err = gen.Outline(prompt, outline, feedback)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return "", false
+		}
+		fmt.Printf("\n%v\n\nApprove this plan? (y = generate, n = cancel, or type feedback): ", outline)
+
+		input, err := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+		switch {
+		case strings.EqualFold(input, "y"):
+			return outline, true
+		case strings.EqualFold(input, "n"), err != nil && input == "":
+			return "", false
+		}
+		feedback = input
+	}
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// templateNames lists the built in prompt templates for flag help.
+func templateNames() string {
+	var names []string
+	for name := range project.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// setFlags returns the config settings given on the command line.
+func setFlags(fs *flag.FlagSet) map[string]string {
+	keys := map[string]bool{}
+	for _, key := range config.Keys {
+		keys[key] = true
 	}
+	set := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		if keys[f.Name] {
+			set[f.Name] = f.Value.String()
+		}
+	})
+	return set
+}
 
-	fmt.Println("Project setup and build complete.")
+// isFlagSet reports whether a top level flag was given on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
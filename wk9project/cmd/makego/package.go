@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/dist"
+)
+
+// runPackage implements `makego package [-targets ...] [-version v] [-out dir] <dir>`.
+func runPackage(args []string) {
+	flags := flag.NewFlagSet("package", flag.ExitOnError)
+	targets := flags.String("targets", "", "comma separated goos/goarch list, defaults to linux, darwin and windows")
+	version := flags.String("version", "v0.0.0", "version stamped into main.version and archive names")
+	out := flags.String("out", "", "output directory, defaults to <dir>/dist")
+	flags.Parse(args)
+
+	dir := flags.Arg(0)
+	if dir == "" {
+		dir = "."
+	}
+	if *out == "" {
+		*out = filepath.Join(dir, "dist")
+	}
+
+	matrix, err := dist.ParseTargets(*targets)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	manifest, err := dist.Package(dist.Options{
+		Dir:     dir,
+		Out:     *out,
+		Version: *version,
+		Targets: matrix,
+		Log:     os.Stdout,
+	})
+	if err != nil {
+		fmt.Println("Error packaging:", err)
+		return
+	}
+	fmt.Printf("Packaged %v %v for %d target(s) into %v.\n", manifest.Module, manifest.Version, len(manifest.Artifacts), *out)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/config"
+	"github.com/jeremycruzz/msds301-wk9/pkg/history"
+	"github.com/jeremycruzz/msds301-wk9/pkg/llm"
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+	"github.com/jeremycruzz/msds301-wk9/pkg/refactor"
+)
+
+// runRefactor implements `makego refactor <dir> -goal GOAL -sessions FILES`.
+func runRefactor(args []string) {
+	flags := flag.NewFlagSet("refactor", flag.ExitOnError)
+	flags.String("apikey", "", "API key for chatgpt")
+	flags.String("profile", "", "config profile to use")
+	goal := flags.String("goal", "", "what the refactoring should achieve")
+	sessions := flags.String("sessions", "", "comma separated recordings or globs replayed against the old and new program")
+	keep := flags.Bool("keep", false, "keep the refactored code even when it fails to build or behaves differently")
+	useGit := flags.Bool("git", false, "commit the refactoring")
+
+	// the directory may come before the flags
+	dir := "."
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		dir, args = args[0], args[1:]
+	}
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+
+	if *goal == "" {
+		fmt.Println("Usage: makego refactor <dir> -goal GOAL -sessions FILES")
+		os.Exit(2)
+	}
+	conf, err := config.Load(config.Options{Flags: setFlags(flags)})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if conf.APIKey == "" {
+		log.Fatal("API key is required. Start with -apikey flag.")
+	}
+	client, err := llm.New(llm.Config{Provider: conf.Provider, Model: conf.Model, Temperature: conf.Temperature}, conf.APIKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	recordings, err := loadRecordings(*sessions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(recordings) == 0 {
+		log.Fatal("At least one recording is needed to compare the old and new program. Record one with makego record and pass it with -sessions.")
+	}
+
+	module, err := refactor.ModulePath(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	old, err := refactor.Read(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	modFiles := readModFiles(dir)
+
+	fmt.Println("Building the current program...")
+	oldBin, err := project.BuildBinary(dir)
+	if err != nil {
+		fmt.Println("Error: the program must build before refactoring:", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(filepath.Dir(oldBin))
+
+	fmt.Printf("Asking chatgpt to refactor %v: %v\n", dir, *goal)
+	response, err := client.AskCustom(refactor.Prompt(module, old, *goal))
+	if err != nil {
+		fmt.Println("Error with chatgpt:", err)
+		os.Exit(1)
+	}
+	files, err := refactor.Parse(response)
+	if err != nil {
+		fmt.Println("Error reading the refactored files:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Writing %v...\n", strings.Join(files.Names(), ", "))
+	if err := refactor.Apply(dir, old, files); err != nil {
+		fmt.Println("Error writing the refactored files:", err)
+		rollback(dir, old, files, modFiles)
+		os.Exit(1)
+	}
+
+	ok, report := checkRefactor(dir, oldBin, recordings, conf.SandboxEnv())
+	if !ok {
+		if *keep {
+			fmt.Println("Keeping the refactored code as asked.")
+		} else {
+			rollback(dir, old, files, modFiles)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("Refactoring complete.")
+
+	if *useGit {
+		body := "Goal: " + *goal + "\n\nRecorded sessions:\n" + report
+		if _, err := history.Commit(dir, "refactor: "+history.Summary(*goal), body); err != nil {
+			fmt.Println("Error committing:", err)
+		}
+	}
+}
+
+// checkRefactor builds and tests the refactored project and compares it
+// with the old binary on the recordings. It returns whether everything
+// passed and the comparison report.
+func checkRefactor(dir, oldBin string, recordings []*refactor.Recording, env []string) (bool, string) {
+	gen := &project.Generator{Log: os.Stdout}
+	if err := gen.Build(dir); err != nil {
+		fmt.Println("Error:", err)
+		return false, ""
+	}
+	if hasTests(dir) {
+		fmt.Println("Running tests...")
+		if err := project.Go(dir, "test", "./..."); err != nil {
+			fmt.Println("Error:", err)
+			return false, ""
+		}
+	}
+
+	newBin, err := project.BuildBinary(dir)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return false, ""
+	}
+	defer os.RemoveAll(filepath.Dir(newBin))
+
+	fmt.Println("Replaying recorded sessions...")
+	results, err := refactor.Compare(oldBin, newBin, recordings, env)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return false, ""
+	}
+	report := refactor.Report(results)
+	fmt.Print(report)
+	if refactor.Unsupported(results) {
+		fmt.Printf("Cannot check this refactoring: the program prints different output on every run, even with %v set. "+
+			"Have it seed its random numbers from %v when it is set, or record sessions that avoid the random parts, and try again.\n",
+			project.SeedEnv, project.SeedEnv)
+	}
+	return refactor.Equivalent(results), report
+}
+
+// hasTests reports whether the module in dir has any test files.
+func hasTests(dir string) bool {
+	found := false
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(path, "_test.go") {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// rollback restores the project files from before the refactoring.
+func rollback(dir string, old, applied refactor.Files, modFiles map[string][]byte) {
+	fmt.Println("Rolling back the refactoring...")
+	if err := refactor.Restore(dir, old, applied); err != nil {
+		fmt.Println("Error restoring files:", err)
+	}
+	for name, data := range modFiles {
+		path := filepath.Join(dir, name)
+		if data == nil {
+			os.Remove(path)
+			continue
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			fmt.Println("Error restoring files:", err)
+		}
+	}
+}
+
+// readModFiles returns go.mod, go.sum and vet.txt, nil for a missing
+// file, so rollback can undo go mod tidy and the vet report of the build.
+func readModFiles(dir string) map[string][]byte {
+	files := map[string][]byte{}
+	for _, name := range []string{"go.mod", "go.sum", "vet.txt"} {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		files[name] = data
+	}
+	return files
+}
+
+func loadRecordings(list string) ([]*refactor.Recording, error) {
+	var recordings []*refactor.Recording
+	for _, pattern := range strings.Split(list, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("no recordings match %v", pattern)
+		}
+		for _, path := range paths {
+			r, err := refactor.LoadRecording(path)
+			if err != nil {
+				return nil, err
+			}
+			recordings = append(recordings, r)
+		}
+	}
+	return recordings, nil
+}
+
+// runRecord implements `makego record <dir|binary> <file> [args...]`.
+func runRecord(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: makego record <dir|binary> <file> [args...]")
+		os.Exit(2)
+	}
+	target, path := args[0], args[1]
+	info, err := os.Stat(target)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bin := target
+	if info.IsDir() {
+		bin, err = project.BuildBinary(target)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.RemoveAll(filepath.Dir(bin))
+	}
+
+	conf, err := config.Load(config.Options{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Recording to %v, use the program as usual...\n", path)
+	if err := refactor.Record(bin, args[2:], conf.SandboxEnv(), os.Stdin, os.Stdout, path); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("Saved %v\n", path)
+}
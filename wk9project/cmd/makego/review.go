@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/config"
+	"github.com/jeremycruzz/msds301-wk9/pkg/llm"
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+	"github.com/jeremycruzz/msds301-wk9/pkg/review"
+)
+
+// runReview implements `makego review [-fix -apikey KEY] <dir>`.
+func runReview(args []string) {
+	flags := flag.NewFlagSet("review", flag.ExitOnError)
+	flags.String("apikey", "", "API key for chatgpt, needed for -fix")
+	flags.String("profile", "", "config profile to use for -fix")
+	fix := flags.Bool("fix", false, "send the report back to chatgpt for a fix round")
+	flags.Parse(args)
+
+	dir := flags.Arg(0)
+	if dir == "" {
+		dir = "."
+	}
+	conf, err := config.Load(config.Options{Flags: setFlags(flags)})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *fix && conf.APIKey == "" {
+		log.Fatal("API key is required for -fix. Start with -apikey flag.")
+	}
+
+	fmt.Printf("Reviewing %v...\n", dir)
+	findings, err := review.Dir(dir)
+	if err != nil {
+		fmt.Println("Error reviewing code:", err)
+		return
+	}
+	report := review.Report(findings)
+	fmt.Print(report)
+	if !*fix || len(findings) == 0 {
+		return
+	}
+
+	// fix round
+	code, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		fmt.Println("Error reading main.go:", err)
+		return
+	}
+
+	client, err := llm.New(llm.Config{Provider: conf.Provider, Model: conf.Model, Temperature: conf.Temperature}, conf.APIKey)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	prompt := project.CodePreamble + "Here is a go program followed by a review of its problems. Fix every problem in the review without changing what the program does.\n\nReview:\n" + report + "\nProgram:\n" + string(code)
+	fmt.Println("Asking chatgpt to fix the problems...")
+	response, err := client.AskCustom(prompt)
+	if err != nil {
+		fmt.Println("Error with chatgpt", err)
+		return
+	}
+
+	gen := &project.Generator{Log: os.Stdout}
+	if err := gen.WriteCode(dir, "main.go", response); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if err := gen.Build(dir); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	findings, err = review.Dir(dir)
+	if err != nil {
+		fmt.Println("Error reviewing code:", err)
+		return
+	}
+	fmt.Print("After fix: ", review.Report(findings))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/config"
+	"github.com/jeremycruzz/msds301-wk9/pkg/llm"
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+	"github.com/jeremycruzz/msds301-wk9/pkg/server"
+)
+
+// runServe implements `makego serve -apikey KEY [-addr localhost:8080]`.
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.String("apikey", "", "API key for chatgpt")
+	flags.String("profile", "", "config profile to use")
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	defaults := config.Defaults()
+	flags.String("output", defaults.Output, "directory projects are generated in")
+	flags.String("template", defaults.Template, "prompt template: "+templateNames()+" or a template file")
+	flags.Int("repairs", 0, "how many times a failed build is sent back to the model")
+	workers := flags.Int("workers", 2, "number of jobs to run at once")
+	queue := flags.Int("queue", 16, "number of jobs that can wait in the queue")
+	flags.Parse(args)
+
+	conf, err := config.Load(config.Options{Flags: setFlags(flags)})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if conf.APIKey == "" {
+		log.Fatal("API key is required. Start with -apikey flag.")
+	}
+	if *workers < 1 {
+		log.Fatal("-workers must be at least 1.")
+	}
+	tmpl, err := project.LoadTemplate(conf.Template)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := llm.New(llm.Config{Provider: conf.Provider, Model: conf.Model, Temperature: conf.Temperature}, conf.APIKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	srv := server.New(client, conf.Output, *workers, *queue)
+	srv.Template = tmpl
+	srv.Repairs = conf.Repairs
+	srv.Env = conf.SandboxEnv()
+	fmt.Printf("Listening on http://%v/projects...\n", *addr)
+	log.Fatal(http.ListenAndServe(*addr, srv))
+}
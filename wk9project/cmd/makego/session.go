@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/config"
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+	"github.com/jeremycruzz/msds301-wk9/pkg/session"
+)
+
+// runSession implements `makego session [-v] <dir|binary> <transcript>...`.
+func runSession(args []string) {
+	flags := flag.NewFlagSet("session", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "print the program output of every session")
+	flags.String("profile", "", "config profile to use")
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		fmt.Println("Usage: makego session [-v] <dir|binary> <transcript>...")
+		os.Exit(2)
+	}
+	conf, err := config.Load(config.Options{Flags: setFlags(flags)})
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	if !runSessions(flags.Arg(0), flags.Args()[1:], conf.SandboxEnv(), *verbose) {
+		os.Exit(1)
+	}
+}
+
+// runSessions runs each transcript against target, a binary or a project
+// directory to build, and reports whether all of them passed. Transcripts
+// without their own environment run with env.
+func runSessions(target string, transcripts, env []string, verbose bool) bool {
+	info, err := os.Stat(target)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return false
+	}
+	bin := target
+	if info.IsDir() {
+		fmt.Printf("Building %v...\n", target)
+		bin, err = project.BuildBinary(target)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return false
+		}
+		defer os.RemoveAll(filepath.Dir(bin))
+	}
+
+	passed := 0
+	for _, path := range transcripts {
+		t, err := session.Load(path)
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		if t.Env == nil {
+			t.Env = env
+		}
+		result, err := session.Run(bin, t)
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		fmt.Printf("%v: %v\n", path, result)
+		if result.Passed {
+			passed++
+		}
+		if verbose || !result.Passed {
+			fmt.Println(result.Output)
+		}
+	}
+
+	fmt.Printf("%d/%d session(s) passed\n", passed, len(transcripts))
+	return passed == len(transcripts)
+}
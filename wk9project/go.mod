@@ -3,3 +3,5 @@ module github.com/jeremycruzz/msds301-wk9
 go 1.21.1
 
 require github.com/jeremycruzz/msds301-wk8 v0.0.0-20231119192107-c7b24be43532 // indirect
+
+require github.com/BurntSushi/toml v1.3.2
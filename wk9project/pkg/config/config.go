@@ -0,0 +1,342 @@
+// Package config resolves makego settings from layered sources: built in
+// defaults, the user config file, the project .makego.toml, MAKEGO_*
+// environment variables and command line flags, each overriding the one
+// before. Config files can define named profiles that are applied on top
+// of the file's own settings.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ProjectFile is the per project config file name.
+const ProjectFile = ".makego.toml"
+
+// Sandbox policies for running generated programs.
+const (
+	// SandboxNone runs generated programs with makego's environment.
+	SandboxNone = "none"
+	// SandboxCleanEnv runs them with only PATH, HOME and the temp dir set,
+	// so API keys and other secrets are not visible to them.
+	SandboxCleanEnv = "clean-env"
+)
+
+// Config is the effective configuration.
+type Config struct {
+	Profile     string
+	Provider    string
+	Model       string
+	Temperature float64
+	Template    string
+	Repairs     int
+	Sandbox     string
+	// Output is the directory projects are generated in.
+	Output string
+	APIKey string
+
+	// Sources maps each setting's key to where its value came from.
+	Sources map[string]string
+}
+
+// Defaults are the built in settings.
+func Defaults() Config {
+	c := Config{
+		Provider: "chatgpt",
+		Template: "default",
+		Sandbox:  SandboxNone,
+		Output:   "..",
+		Sources:  map[string]string{},
+	}
+	for _, key := range Keys {
+		c.Sources[key] = "default"
+	}
+	return c
+}
+
+// Keys are the setting names used in files, MAKEGO_ variables and flags.
+var Keys = []string{"profile", "provider", "model", "temperature", "template", "repairs", "sandbox", "output", "apikey"}
+
+// settings is one layer; nil fields are not set by the layer.
+type settings struct {
+	Profile     *string  `toml:"profile"`
+	Provider    *string  `toml:"provider"`
+	Model       *string  `toml:"model"`
+	Temperature *float64 `toml:"temperature"`
+	Template    *string  `toml:"template"`
+	Repairs     *int     `toml:"repairs"`
+	Sandbox     *string  `toml:"sandbox"`
+	Output      *string  `toml:"output"`
+	APIKey      *string  `toml:"apikey"`
+}
+
+type file struct {
+	settings
+	Profiles map[string]settings `toml:"profiles"`
+
+	path string
+}
+
+// Options say where to look for configuration.
+type Options struct {
+	// UserFile defaults to ~/.config/makego/config.toml.
+	UserFile string
+	// ProjectFile defaults to .makego.toml in the working directory.
+	ProjectFile string
+	// Env looks up environment variables, os.LookupEnv by default.
+	Env func(string) (string, bool)
+	// Flags holds the flags given on the command line by setting key.
+	Flags map[string]string
+}
+
+// UserFile returns the default user config file path.
+func UserFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "makego", "config.toml")
+}
+
+// Load resolves the effective configuration.
+func Load(opts Options) (Config, error) {
+	if opts.UserFile == "" {
+		opts.UserFile = UserFile()
+	}
+	if opts.ProjectFile == "" {
+		opts.ProjectFile = ProjectFile
+	}
+	if opts.Env == nil {
+		opts.Env = os.LookupEnv
+	}
+
+	var files []*file
+	for _, path := range []string{opts.UserFile, opts.ProjectFile} {
+		f, err := readFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		if f != nil {
+			files = append(files, f)
+		}
+	}
+
+	env, err := envLayer(opts.Env)
+	if err != nil {
+		return Config{}, err
+	}
+	flags, err := parseLayer(opts.Flags)
+	if err != nil {
+		return Config{}, fmt.Errorf("flags: %w", err)
+	}
+
+	// the profile is picked first since it decides which sections apply
+	c := Defaults()
+	for _, f := range files {
+		c.apply(settings{Profile: f.Profile}, f.path)
+	}
+	c.apply(settings{Profile: env.Profile}, "env")
+	c.apply(settings{Profile: flags.Profile}, "flag")
+
+	for _, f := range files {
+		base := f.settings
+		base.Profile = nil
+		c.apply(base, f.path)
+		if c.Profile == "" {
+			continue
+		}
+		if p, ok := f.Profiles[c.Profile]; ok {
+			p.Profile = nil
+			c.apply(p, fmt.Sprintf("%v [profiles.%v]", f.path, c.Profile))
+		}
+	}
+	if c.Profile != "" && !hasProfile(files, c.Profile) {
+		return Config{}, fmt.Errorf("profile %q is not defined in any config file", c.Profile)
+	}
+	env.Profile, flags.Profile = nil, nil
+	c.apply(env, "env")
+	c.apply(flags, "flag")
+
+	return c, c.validate()
+}
+
+func hasProfile(files []*file, name string) bool {
+	for _, f := range files {
+		if _, ok := f.Profiles[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func readFile(path string) (*file, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f := &file{path: path}
+	md, err := toml.DecodeFile(path, f)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %v: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%v: unknown setting %v", path, undecoded[0])
+	}
+	return f, nil
+}
+
+// envLayer reads MAKEGO_<KEY> variables.
+func envLayer(lookup func(string) (string, bool)) (settings, error) {
+	values := map[string]string{}
+	for _, key := range Keys {
+		if v, ok := lookup("MAKEGO_" + strings.ToUpper(key)); ok {
+			values[key] = v
+		}
+	}
+	s, err := parseLayer(values)
+	if err != nil {
+		return s, fmt.Errorf("environment: %w", err)
+	}
+	return s, nil
+}
+
+// parseLayer turns key/value strings into a layer.
+func parseLayer(values map[string]string) (settings, error) {
+	var s settings
+	for key, v := range values {
+		v := v
+		switch key {
+		case "profile":
+			s.Profile = &v
+		case "provider":
+			s.Provider = &v
+		case "model":
+			s.Model = &v
+		case "template":
+			s.Template = &v
+		case "sandbox":
+			s.Sandbox = &v
+		case "output":
+			s.Output = &v
+		case "apikey":
+			s.APIKey = &v
+		case "temperature":
+			t, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return s, fmt.Errorf("temperature: %w", err)
+			}
+			s.Temperature = &t
+		case "repairs":
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return s, fmt.Errorf("repairs: %w", err)
+			}
+			s.Repairs = &n
+		default:
+			return s, fmt.Errorf("unknown setting %v", key)
+		}
+	}
+	return s, nil
+}
+
+func (c *Config) apply(s settings, source string) {
+	set(&c.Profile, s.Profile, c.Sources, "profile", source)
+	set(&c.Provider, s.Provider, c.Sources, "provider", source)
+	set(&c.Model, s.Model, c.Sources, "model", source)
+	set(&c.Temperature, s.Temperature, c.Sources, "temperature", source)
+	set(&c.Template, s.Template, c.Sources, "template", source)
+	set(&c.Repairs, s.Repairs, c.Sources, "repairs", source)
+	set(&c.Sandbox, s.Sandbox, c.Sources, "sandbox", source)
+	set(&c.Output, s.Output, c.Sources, "output", source)
+	set(&c.APIKey, s.APIKey, c.Sources, "apikey", source)
+}
+
+func set[T any](dst *T, src *T, sources map[string]string, key, source string) {
+	if src == nil {
+		return
+	}
+	*dst = *src
+	switch source {
+	case "env":
+		source = "env MAKEGO_" + strings.ToUpper(key)
+	case "flag":
+		source = "flag -" + key
+	}
+	sources[key] = source
+}
+
+func (c Config) validate() error {
+	switch c.Sandbox {
+	case SandboxNone, SandboxCleanEnv:
+	default:
+		return fmt.Errorf("sandbox must be %v or %v, got %q", SandboxNone, SandboxCleanEnv, c.Sandbox)
+	}
+	if c.Repairs < 0 {
+		return fmt.Errorf("repairs must not be negative")
+	}
+	return nil
+}
+
+// SandboxEnv returns the environment generated programs run with, or nil
+// to inherit makego's.
+func (c Config) SandboxEnv() []string {
+	if c.Sandbox != SandboxCleanEnv {
+		return nil
+	}
+	env := []string{}
+	for _, key := range []string{"PATH", "HOME", "TMPDIR", "TEMP", "TMP", "SYSTEMROOT"} {
+		if v, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+v)
+		}
+	}
+	return env
+}
+
+// Show formats every setting with its value and source. The API key is
+// masked.
+func (c Config) Show() string {
+	values := map[string]string{
+		"profile":     c.Profile,
+		"provider":    c.Provider,
+		"model":       c.Model,
+		"temperature": strconv.FormatFloat(c.Temperature, 'g', -1, 64),
+		"template":    c.Template,
+		"repairs":     strconv.Itoa(c.Repairs),
+		"sandbox":     c.Sandbox,
+		"output":      c.Output,
+		"apikey":      mask(c.APIKey),
+	}
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+	keys := append([]string(nil), Keys...)
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := values[key]
+		if value == "" {
+			value = `""`
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\n", key, value, c.Sources[key])
+	}
+	w.Flush()
+	return b.String()
+}
+
+func mask(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:3] + strings.Repeat("*", len(key)-7) + key[len(key)-4:]
+}
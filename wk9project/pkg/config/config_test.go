@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// load resolves a configuration from the given file contents, MAKEGO_
+// variables and flags. An empty file content means there is no file.
+func load(t *testing.T, user, project string, env, flags map[string]string) (Config, error) {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if content != "" {
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return path
+	}
+	return Load(Options{
+		UserFile:    write("user.toml", user),
+		ProjectFile: write("project.toml", project),
+		Env: func(key string) (string, bool) {
+			v, ok := env[key]
+			return v, ok
+		},
+		Flags: flags,
+	})
+}
+
+func TestLoadLayers(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		project string
+		env     map[string]string
+		flags   map[string]string
+		model   string
+		source  string
+	}{
+		{"default", "", "", nil, nil, "", "default"},
+		{"user file", `model = "u"`, "", nil, nil, "u", "user.toml"},
+		{"project over user", `model = "u"`, `model = "p"`, nil, nil, "p", "project.toml"},
+		{"env over files", `model = "u"`, `model = "p"`, map[string]string{"MAKEGO_MODEL": "e"}, nil, "e", "env MAKEGO_MODEL"},
+		{"flag over env", `model = "u"`, "", map[string]string{"MAKEGO_MODEL": "e"}, map[string]string{"model": "f"}, "f", "flag -model"},
+		{
+			name:   "profile over its file",
+			user:   "profile = \"fast\"\nmodel = \"u\"\n[profiles.fast]\nmodel = \"fast\"\n",
+			model:  "fast",
+			source: "user.toml [profiles.fast]",
+		},
+		{
+			name:    "project file over user profile",
+			user:    "profile = \"fast\"\n[profiles.fast]\nmodel = \"fast\"\n",
+			project: `model = "p"`,
+			model:   "p",
+			source:  "project.toml",
+		},
+		{
+			name:   "profile picked by flag",
+			user:   "[profiles.fast]\nmodel = \"fast\"\n[profiles.slow]\nmodel = \"slow\"\n",
+			env:    map[string]string{"MAKEGO_PROFILE": "fast"},
+			flags:  map[string]string{"profile": "slow"},
+			model:  "slow",
+			source: "user.toml [profiles.slow]",
+		},
+		{
+			name:   "env over profile",
+			user:   "profile = \"fast\"\n[profiles.fast]\nmodel = \"fast\"\n",
+			env:    map[string]string{"MAKEGO_MODEL": "e"},
+			model:  "e",
+			source: "env MAKEGO_MODEL",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := load(t, tt.user, tt.project, tt.env, tt.flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			source := c.Sources["model"]
+			if dir, file := filepath.Split(source); dir != "" {
+				source = file
+			}
+			if c.Model != tt.model || source != tt.source {
+				t.Errorf("model = %q from %q, want %q from %q", c.Model, c.Sources["model"], tt.model, tt.source)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		user  string
+		env   map[string]string
+		flags map[string]string
+		want  string
+	}{
+		{"unknown setting", `color = "red"`, nil, nil, "unknown setting color"},
+		{"undefined profile", "", nil, map[string]string{"profile": "fast"}, `profile "fast" is not defined`},
+		{"bad sandbox", `sandbox = "jail"`, nil, nil, "sandbox must be none or clean-env"},
+		{"negative repairs", "", map[string]string{"MAKEGO_REPAIRS": "-1"}, nil, "repairs must not be negative"},
+		{"bad temperature", "", nil, map[string]string{"temperature": "hot"}, "flags: temperature"},
+		{"bad env repairs", "", map[string]string{"MAKEGO_REPAIRS": "two"}, nil, "environment: repairs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.user, "", tt.env, tt.flags)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{"", ""},
+		{"short", "*****"},
+		{"12345678", "********"},
+		{"sk-abcdefgh1234", "sk-********1234"},
+	}
+	for _, tt := range tests {
+		if got := mask(tt.key); got != tt.want {
+			t.Errorf("mask(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestShowMasksAPIKey(t *testing.T) {
+	c, err := load(t, "", "", map[string]string{"MAKEGO_APIKEY": "sk-secretsecret1234"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	show := c.Show()
+	if strings.Contains(show, "secret") || !strings.Contains(show, "env MAKEGO_APIKEY") {
+		t.Errorf("Show() =\n%v", show)
+	}
+}
@@ -0,0 +1,83 @@
+package dist
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// writeTarGz writes files flat into a gzipped tarball with fixed
+// ownership and timestamps.
+func writeTarGz(w io.Writer, files []string, modTime time.Time) error {
+	gz, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(gz)
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:    filepath.Base(path),
+			Mode:    int64(mode(info)),
+			Size:    info.Size(),
+			ModTime: modTime,
+			Format:  tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if err := copyFile(tw, path); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeZip writes files flat into a zip archive with fixed timestamps.
+func writeZip(w io.Writer, files []string, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		hdr := &zip.FileHeader{Name: filepath.Base(path), Method: zip.Deflate, Modified: modTime}
+		hdr.SetMode(mode(info))
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if err := copyFile(fw, path); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// mode normalizes permissions so the umask does not leak into archives.
+func mode(info os.FileInfo) os.FileMode {
+	if info.Mode()&0111 != 0 {
+		return 0755
+	}
+	return 0644
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,258 @@
+// Package dist cross compiles a generated program for a set of platforms
+// and packages the binaries as checksummed archives with a manifest of the
+// module dependencies. Builds use -trimpath, no VCS stamping and fixed
+// archive timestamps so the same source gives the same archives.
+package dist
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTargets is the platform matrix used when none is given.
+var DefaultTargets = []Target{
+	{"linux", "amd64"},
+	{"linux", "arm64"},
+	{"darwin", "amd64"},
+	{"darwin", "arm64"},
+	{"windows", "amd64"},
+}
+
+// Target is a GOOS/GOARCH pair.
+type Target struct {
+	OS   string `json:"goos"`
+	Arch string `json:"goarch"`
+}
+
+func (t Target) String() string {
+	return t.OS + "/" + t.Arch
+}
+
+// ParseTargets parses a comma separated list like "linux/amd64,windows/amd64".
+func ParseTargets(s string) ([]Target, error) {
+	var targets []Target
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		goos, goarch, ok := strings.Cut(field, "/")
+		if !ok || goos == "" || goarch == "" {
+			return nil, fmt.Errorf("target %q is not in goos/goarch form", field)
+		}
+		targets = append(targets, Target{goos, goarch})
+	}
+	return targets, nil
+}
+
+// Options configure a packaging run.
+type Options struct {
+	// Dir is the module to package.
+	Dir string
+	// Out is where archives, checksums and the manifest are written.
+	Out     string
+	Version string
+	Targets []Target
+	Log     io.Writer
+}
+
+// Module is a module dependency as reported by go list -m.
+type Module struct {
+	Path     string  `json:"path"`
+	Version  string  `json:"version,omitempty"`
+	Sum      string  `json:"sum,omitempty"`
+	Indirect bool    `json:"indirect,omitempty"`
+	Replace  *Module `json:"replace,omitempty"`
+}
+
+// Artifact is one packaged binary.
+type Artifact struct {
+	Target  Target `json:"target"`
+	Archive string `json:"archive"`
+	SHA256  string `json:"sha256"`
+	Size    int64  `json:"size"`
+}
+
+// Manifest describes everything that went into a packaging run.
+type Manifest struct {
+	Module       string     `json:"module"`
+	Version      string     `json:"version"`
+	GoVersion    string     `json:"goVersion"`
+	Toolchain    string     `json:"toolchain"`
+	Flags        []string   `json:"buildFlags"`
+	Dependencies []Module   `json:"dependencies"`
+	Artifacts    []Artifact `json:"artifacts"`
+}
+
+// Package builds and archives the module in opts.Dir for every target.
+func Package(opts Options) (*Manifest, error) {
+	if opts.Log == nil {
+		opts.Log = io.Discard
+	}
+	if len(opts.Targets) == 0 {
+		opts.Targets = DefaultTargets
+	}
+	if opts.Version == "" {
+		opts.Version = "v0.0.0"
+	}
+
+	mods, err := listModules(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	mainMod, deps := mods[0], mods[1:]
+
+	toolchain, err := goOutput(opts.Dir, nil, "env", "GOVERSION")
+	if err != nil {
+		return nil, err
+	}
+	goVersion, err := goOutput(opts.Dir, nil, "list", "-m", "-f", "{{.GoVersion}}")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(opts.Out, 0755); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+	staging, err := os.MkdirTemp("", "makego-dist")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	name := filepath.Base(mainMod.Path)
+	flags := []string{"-trimpath", "-buildvcs=false", "-ldflags=-s -w -buildid= -X main.version=" + opts.Version}
+	manifest := &Manifest{
+		Module:       mainMod.Path,
+		Version:      opts.Version,
+		GoVersion:    goVersion,
+		Toolchain:    toolchain,
+		Flags:        flags,
+		Dependencies: deps,
+	}
+
+	modTime := sourceDate()
+	var sums strings.Builder
+	for _, target := range opts.Targets {
+		fmt.Fprintf(opts.Log, "Building %v...\n", target)
+		binary := name
+		if target.OS == "windows" {
+			binary += ".exe"
+		}
+		binPath := filepath.Join(staging, target.OS+"_"+target.Arch, binary)
+		env := []string{"GOOS=" + target.OS, "GOARCH=" + target.Arch, "CGO_ENABLED=0"}
+		args := append([]string{"build"}, flags...)
+		if _, err := goOutput(opts.Dir, env, append(args, "-o", binPath, ".")...); err != nil {
+			return nil, fmt.Errorf("building %v: %w", target, err)
+		}
+
+		files := []string{binPath}
+		for _, doc := range []string{"README.md", "LICENSE"} {
+			if _, err := os.Stat(filepath.Join(opts.Dir, doc)); err == nil {
+				files = append(files, filepath.Join(opts.Dir, doc))
+			}
+		}
+
+		archive := fmt.Sprintf("%v_%v_%v_%v", name, strings.TrimPrefix(opts.Version, "v"), target.OS, target.Arch)
+		var buf bytes.Buffer
+		if target.OS == "windows" {
+			archive += ".zip"
+			err = writeZip(&buf, files, modTime)
+		} else {
+			archive += ".tar.gz"
+			err = writeTarGz(&buf, files, modTime)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("archiving %v: %w", target, err)
+		}
+		if err := os.WriteFile(filepath.Join(opts.Out, archive), buf.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(buf.Bytes())
+		artifact := Artifact{Target: target, Archive: archive, SHA256: hex.EncodeToString(sum[:]), Size: int64(buf.Len())}
+		manifest.Artifacts = append(manifest.Artifacts, artifact)
+		fmt.Fprintf(&sums, "%v  %v\n", artifact.SHA256, archive)
+		fmt.Fprintf(opts.Log, "  %v %v\n", archive, artifact.SHA256)
+	}
+
+	if err := os.WriteFile(filepath.Join(opts.Out, "SHA256SUMS"), []byte(sums.String()), 0644); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(opts.Out, "manifest.json"), append(data, '\n'), 0644); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// listModules returns the main module followed by its dependencies.
+func listModules(dir string) ([]Module, error) {
+	out, err := goOutput(dir, nil, "list", "-m", "-json", "all")
+	if err != nil {
+		return nil, err
+	}
+
+	var mods []Module
+	dec := json.NewDecoder(strings.NewReader(out))
+	for {
+		var m struct {
+			Path     string
+			Version  string
+			Sum      string
+			Indirect bool
+			Replace  *struct{ Path, Version, Sum string }
+		}
+		if err := dec.Decode(&m); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("reading go list output: %w", err)
+		}
+		mod := Module{Path: m.Path, Version: m.Version, Sum: m.Sum, Indirect: m.Indirect}
+		if m.Replace != nil {
+			mod.Replace = &Module{Path: m.Replace.Path, Version: m.Replace.Version, Sum: m.Replace.Sum}
+		}
+		mods = append(mods, mod)
+	}
+	if len(mods) == 0 {
+		return nil, errors.New("go list returned no modules")
+	}
+	return mods, nil
+}
+
+// sourceDate is the timestamp written into archives, taken from
+// SOURCE_DATE_EPOCH when set so builds can be reproduced exactly.
+func sourceDate() time.Time {
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		return time.Unix(epoch, 0).UTC()
+	}
+	// zip cannot store times before 1980
+	return time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func goOutput(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go %v: %w\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+	return strings.TrimSpace(string(out)), nil
+}
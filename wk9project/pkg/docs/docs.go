@@ -0,0 +1,197 @@
+// Package docs asks the model to document a generated program: a package
+// doc comment in main.go and a README with usage, flags and an example
+// session. The request includes the code and the program's real -h
+// output, and the example commands in the README are run against the
+// built binary to check they print what the README claims.
+package docs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+)
+
+// usageTimeout bounds running the program with -h, which programs that
+// do not use the flag package treat as a normal run.
+const usageTimeout = 5 * time.Second
+
+// Asker is the part of the chatgpt client used for docs.
+type Asker interface {
+	AskCustom(prompt string) (string, error)
+}
+
+// Options describe the program to document.
+type Options struct {
+	Dir  string
+	Name string
+	// Env is the environment the README commands run with; nil inherits
+	// makego's.
+	Env []string
+	Log io.Writer
+}
+
+// Write adds a package doc comment to main.go, writes README.md and
+// checks the README's example commands. The checks are returned even
+// when some of them fail; only being unable to write the docs is an
+// error.
+func Write(client Asker, opts Options) ([]Command, error) {
+	logf := func(format string, args ...any) {
+		fmt.Fprintf(opts.Log, format, args...)
+	}
+	path := filepath.Join(opts.Dir, "main.go")
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading main.go: %w", err)
+	}
+
+	bin, err := project.BuildBinary(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(filepath.Dir(bin))
+	usage := usage(bin)
+
+	// package doc comment
+	logf("Asking chatgpt for a package doc comment...\n")
+	response, err := client.AskCustom(commentPrompt(opts.Name, string(code), usage))
+	if err != nil {
+		return nil, fmt.Errorf("asking chatgpt: %w", err)
+	}
+	documented, err := setPackageDoc(code, response)
+	if err != nil {
+		logf("Could not add the doc comment, leaving main.go as is: %v\n", err)
+	} else {
+		if err := os.WriteFile(path, documented, 0644); err != nil {
+			return nil, fmt.Errorf("writing main.go: %w", err)
+		}
+		code = documented
+		if err := project.Go(opts.Dir, "build"); err != nil {
+			return nil, fmt.Errorf("building the documented project: %w", err)
+		}
+	}
+
+	// readme
+	logf("Asking chatgpt for a README...\n")
+	readme, err := client.AskCustom(readmePrompt(opts.Name, string(code), usage))
+	if err != nil {
+		return nil, fmt.Errorf("asking chatgpt: %w", err)
+	}
+	readme = unwrap(readme)
+	logf("Writing to README.md...\n")
+	if err := os.WriteFile(filepath.Join(opts.Dir, "README.md"), []byte(readme), 0644); err != nil {
+		return nil, fmt.Errorf("writing README.md: %w", err)
+	}
+
+	logf("Checking README commands...\n")
+	commands := Commands(readme, opts.Name)
+	for i := range commands {
+		commands[i].Check(bin, opts.Env)
+	}
+	return commands, nil
+}
+
+// usage returns what the program prints for -h, or "" if it does not
+// look like flag usage.
+func usage(bin string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), usageTimeout)
+	defer cancel()
+	out, _ := exec.CommandContext(ctx, bin, "-h").CombinedOutput()
+	if ctx.Err() != nil || !bytes.Contains(out, []byte("Usage")) {
+		return ""
+	}
+	return string(out)
+}
+
+func commentPrompt(name, code, usage string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Here is a go program called %v. Write the package doc comment for its main.go: start with \"%v\" followed by what the program does, then explain how to run it and how it works in a few short paragraphs. Only describe what the code really does. ", name, capitalize(name))
+	b.WriteString("Only respond with the comment itself, every line starting with //, and nothing else.\n\n")
+	if usage != "" {
+		fmt.Fprintf(&b, "Output of running it with -h:\n%v\n", usage)
+	}
+	fmt.Fprintf(&b, "Program:\n%v", code)
+	return b.String()
+}
+
+func readmePrompt(name, code, usage string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Here is a go program called %v. Write a README.md for it in markdown with a short description, how to build it (go build), usage with every flag it accepts, an explanation of how it works and an example session. Only describe what the code really does. ", name)
+	fmt.Fprintf(&b, "Put example commands in ```sh blocks with each command on its own line starting with \"$ ./%v\", followed by the exact output the program prints for it. ", name)
+	fmt.Fprintf(&b, "Show input typed into an interactive program by piping it in, like \"$ printf '5\\nq\\n' | ./%v\". ", name)
+	b.WriteString("Only respond with the contents of the README and nothing else.\n\n")
+	if usage != "" {
+		fmt.Fprintf(&b, "Output of running it with -h:\n%v\n", usage)
+	}
+	fmt.Fprintf(&b, "Program:\n%v", code)
+	return b.String()
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// unwrap removes a fence around the whole response.
+func unwrap(response string) string {
+	s := strings.TrimSpace(response)
+	lines := strings.Split(s, "\n")
+	if len(lines) > 2 && strings.HasPrefix(lines[0], "```") && strings.TrimSpace(lines[len(lines)-1]) == "```" && !strings.HasPrefix(lines[0], "```sh") {
+		s = strings.Join(lines[1:len(lines)-1], "\n")
+	}
+	return strings.TrimSpace(s) + "\n"
+}
+
+// setPackageDoc replaces the package doc comment of code with the comment
+// in response.
+func setPackageDoc(code []byte, response string) ([]byte, error) {
+	var comment []string
+	for _, line := range strings.Split(unwrap(response), "\n") {
+		line = strings.TrimRight(line, " \t")
+		switch {
+		case strings.HasPrefix(line, "package "):
+			continue
+		case strings.HasPrefix(line, "//"):
+			comment = append(comment, line)
+		case line == "":
+			comment = append(comment, "//")
+		default:
+			comment = append(comment, "// "+line)
+		}
+	}
+	for len(comment) > 0 && comment[len(comment)-1] == "//" {
+		comment = comment[:len(comment)-1]
+	}
+	if len(comment) == 0 {
+		return nil, fmt.Errorf("the response has no comment")
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", code, parser.ParseComments|parser.PackageClauseOnly)
+	if err != nil {
+		return nil, err
+	}
+	start := fset.Position(file.Package).Offset
+	if file.Doc != nil {
+		start = fset.Position(file.Doc.Pos()).Offset
+	}
+	end := fset.Position(file.Package).Offset
+
+	var b bytes.Buffer
+	b.Write(code[:start])
+	b.WriteString(strings.Join(comment, "\n") + "\n")
+	b.Write(code[end:])
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,179 @@
+package docs
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/spec"
+)
+
+// Command is an example command from a README, with the output the README
+// says it prints.
+type Command struct {
+	Line    int
+	Text    string
+	Example spec.Example
+	// Skipped is set for commands that do not run the program, like
+	// go build.
+	Skipped bool
+	Passed  bool
+	Detail  string
+}
+
+// Check runs the command against bin.
+func (c *Command) Check(bin string, env []string) {
+	if c.Skipped {
+		return
+	}
+	c.Passed, c.Detail = spec.RunExample(bin, c.Example, env)
+}
+
+// Commands finds the example commands in a README: lines starting with $
+// in fenced code blocks. The lines after a command, up to the next command
+// or the end of the block, are its expected output.
+func Commands(readme, name string) []Command {
+	var commands []Command
+	var current *Command
+	inBlock := false
+	for i, line := range strings.Split(readme, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inBlock = !inBlock
+			current = nil
+			continue
+		}
+		if !inBlock {
+			continue
+		}
+		if text, ok := strings.CutPrefix(trimmed, "$ "); ok {
+			commands = append(commands, parseCommand(i+1, strings.TrimSpace(text), name))
+			current = &commands[len(commands)-1]
+			continue
+		}
+		if current != nil && trimmed != "" && trimmed != "..." {
+			current.Example.Output = append(current.Example.Output, trimmed)
+		}
+	}
+	return commands
+}
+
+// parseCommand understands `./name args`, `go run . args` and either of
+// those with input piped in from printf or echo.
+func parseCommand(line int, text, name string) Command {
+	c := Command{Line: line, Text: text, Example: spec.Example{Name: fmt.Sprintf("README line %d", line)}}
+
+	run := text
+	if before, after, ok := strings.Cut(text, "|"); ok {
+		input, ok := pipedInput(strings.TrimSpace(before))
+		if !ok {
+			c.Skipped = true
+			return c
+		}
+		c.Example.Input = input
+		run = strings.TrimSpace(after)
+	}
+
+	words, ok := split(run)
+	if !ok || len(words) == 0 {
+		c.Skipped = true
+		return c
+	}
+	switch {
+	case words[0] == "./"+name || words[0] == name || words[0] == "./"+name+".exe" || words[0] == name+".exe":
+		c.Example.Args = words[1:]
+	case len(words) >= 3 && words[0] == "go" && words[1] == "run" && words[2] == ".":
+		c.Example.Args = words[3:]
+	default:
+		c.Skipped = true
+	}
+	return c
+}
+
+// pipedInput returns the text printf or echo writes.
+func pipedInput(command string) (string, bool) {
+	words, ok := split(command)
+	if !ok || len(words) < 2 {
+		return "", false
+	}
+	switch words[0] {
+	case "printf":
+		return unescape(words[1]), true
+	case "echo":
+		if words[1] == "-e" {
+			return unescape(strings.Join(words[2:], " ")) + "\n", true
+		}
+		return strings.Join(words[1:], " ") + "\n", true
+	}
+	return "", false
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\\`, `\`).Replace(s)
+}
+
+// split breaks a command into words, honoring single and double quotes.
+func split(command string) ([]string, bool) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case strings.ContainsRune("<>;&`$", r):
+			// redirects and other shell syntax are not supported
+			return nil, false
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, false
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, true
+}
+
+// Report formats the command checks.
+func Report(commands []Command) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	passed, checked := 0, 0
+	for _, c := range commands {
+		status := "skipped"
+		if !c.Skipped {
+			checked++
+			status = "FAILED"
+			if c.Passed {
+				status = "ok"
+				passed++
+			}
+		}
+		fmt.Fprintf(w, "line %d\t%v\t%v", c.Line, status, c.Text)
+		if c.Detail != "" {
+			fmt.Fprintf(w, "\t%v", c.Detail)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	fmt.Fprintf(&b, "%d/%d README command(s) work\n", passed, checked)
+	return b.String()
+}
@@ -0,0 +1,275 @@
+// Package eval runs a suite of benchmark programs through makego across
+// providers, models and prompt templates and scores the results.
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/llm"
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+	"github.com/jeremycruzz/msds301-wk9/pkg/spec"
+)
+
+// Suite is a benchmark definition, loaded from JSON.
+type Suite struct {
+	Name  string `json:"name"`
+	Cases []Case `json:"cases"`
+	// Models are the provider settings to compare.
+	Models []llm.Config `json:"models"`
+	// Templates are built in template names or template files.
+	Templates []string `json:"templates"`
+	// Runs is how many times each case is generated per model and template.
+	Runs    int `json:"runs"`
+	Repairs int `json:"repairs"`
+	// K lists the k values pass@k is reported for.
+	K []int `json:"k"`
+	// Pricing maps a model config (as printed, e.g. "openai/gpt-4o") to its price.
+	Pricing map[string]llm.Price `json:"pricing"`
+
+	dir string
+}
+
+// Case is one benchmark program: a prompt with golden examples, or a spec.
+type Case struct {
+	Name     string         `json:"name"`
+	Prompt   string         `json:"prompt,omitempty"`
+	Examples []spec.Example `json:"examples,omitempty"`
+	// Spec is a spec file relative to the suite. Its examples, sessions
+	// and tests are used as golden outputs and tests.
+	Spec string `json:"spec,omitempty"`
+}
+
+// Load reads a suite file and fills in defaults.
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Suite
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing %v: %w", path, err)
+	}
+	s.dir = filepath.Dir(path)
+	if len(s.Models) == 0 {
+		s.Models = []llm.Config{{Provider: "chatgpt"}}
+	}
+	if len(s.Templates) == 0 {
+		s.Templates = []string{"default"}
+	}
+	if s.Runs < 1 {
+		s.Runs = 1
+	}
+	if len(s.K) == 0 {
+		s.K = []int{1, s.Runs}
+	}
+	for _, c := range s.Cases {
+		if c.Prompt == "" && c.Spec == "" {
+			return nil, fmt.Errorf("%v: case %v needs a prompt or a spec", path, c.Name)
+		}
+	}
+	return &s, nil
+}
+
+// Run is the outcome of generating one case once.
+type Run struct {
+	Case     string  `json:"case"`
+	Model    string  `json:"model"`
+	Template string  `json:"template"`
+	N        int     `json:"n"`
+	Build    bool    `json:"build"`
+	Tests    *bool   `json:"tests,omitempty"`
+	Golden   *bool   `json:"golden,omitempty"`
+	Repairs  int     `json:"repairs"`
+	Prompt   int     `json:"promptTokens"`
+	Output   int     `json:"completionTokens"`
+	Cost     float64 `json:"cost"`
+	Seconds  float64 `json:"seconds"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Options configure an evaluation.
+type Options struct {
+	APIKey string
+	// Root is where the generated projects are kept.
+	Root     string
+	Parallel int
+	Log      io.Writer
+	// Env is the environment the generated programs are tested and run
+	// with, nil inherits makego's.
+	Env []string
+}
+
+type task struct {
+	c        Case
+	model    llm.Config
+	template string
+	n        int
+}
+
+// Evaluate runs every case of the suite Runs times for every model and
+// template, Parallel at a time.
+func Evaluate(s *Suite, opts Options) ([]Run, error) {
+	if opts.Parallel < 1 {
+		opts.Parallel = 1
+	}
+	if opts.Log == nil {
+		opts.Log = io.Discard
+	}
+
+	templates := map[string]string{}
+	for _, name := range s.Templates {
+		tmpl, err := project.LoadTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		templates[name] = tmpl
+	}
+	for _, model := range s.Models {
+		if _, err := llm.New(model, opts.APIKey); err != nil {
+			return nil, err
+		}
+	}
+
+	var tasks []task
+	for _, c := range s.Cases {
+		for _, model := range s.Models {
+			for _, name := range s.Templates {
+				for n := 1; n <= s.Runs; n++ {
+					tasks = append(tasks, task{c, model, name, n})
+				}
+			}
+		}
+	}
+
+	runs := make([]Run, len(tasks))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	var logMu sync.Mutex
+	for w := 0; w < opts.Parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				runs[i] = s.run(tasks[i], i, templates[tasks[i].template], opts)
+				logMu.Lock()
+				fmt.Fprintf(opts.Log, "[%d/%d] %v %v %v #%d: build=%v repairs=%d %v\n", i+1, len(tasks),
+					runs[i].Case, runs[i].Model, runs[i].Template, runs[i].N, runs[i].Build, runs[i].Repairs, runs[i].Error)
+				logMu.Unlock()
+			}
+		}()
+	}
+	for i := range tasks {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return runs, nil
+}
+
+func (s *Suite) run(t task, i int, tmpl string, opts Options) (r Run) {
+	r = Run{Case: t.c.Name, Model: t.model.String(), Template: t.template, N: t.n}
+	start := time.Now()
+	defer func() { r.Seconds = time.Since(start).Seconds() }()
+
+	prompt := t.c.Prompt
+	var cs *spec.Spec
+	if t.c.Spec != "" {
+		var err error
+		cs, err = spec.Load(filepath.Join(s.dir, t.c.Spec))
+		if err != nil {
+			r.Error = err.Error()
+			return r
+		}
+		prompt = cs.Prompt()
+	} else if len(t.c.Examples) > 0 {
+		// a plain prompt case: every example is a golden output
+		cs = &spec.Spec{Description: prompt, Examples: t.c.Examples}
+		for _, e := range t.c.Examples {
+			cs.Requirements = append(cs.Requirements, spec.Requirement{ID: e.Name, Example: e.Name})
+		}
+	}
+
+	client, err := llm.New(t.model, opts.APIKey)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	metered := llm.Meter(client)
+	gen := &project.Generator{Client: metered, Log: io.Discard, Env: opts.Env}
+	res, err := gen.Create(project.Options{
+		Name:     fmt.Sprintf("%v_%03d", t.c.Name, i+1),
+		Prompt:   prompt,
+		Root:     opts.Root,
+		Template: tmpl,
+		Repairs:  s.Repairs,
+	})
+	if res != nil {
+		r.Repairs = res.Repairs
+	}
+	r.Build = err == nil
+	if err != nil {
+		r.Error = firstLine(err.Error())
+	}
+
+	if cs != nil {
+		cs.Env = opts.Env
+		r.Tests, r.Golden = score(res, r.Build, cs)
+	}
+
+	r.Prompt, r.Output = metered.Tokens()
+	r.Cost = s.Pricing[r.Model].Cost(r.Prompt, r.Output)
+	return r
+}
+
+// score checks the spec's tests and golden examples and sessions without
+// asking the model. It returns nil for a category the spec does not cover
+// and false for every covered category when the build failed.
+func score(res *project.Result, built bool, cs *spec.Spec) (tests, golden *bool) {
+	var results []spec.Result
+	if built {
+		var err error
+		results, err = spec.Check(res.Dir, cs, nil)
+		if err != nil {
+			built = false
+		}
+	}
+	if !built {
+		results = nil
+		for _, req := range cs.Requirements {
+			results = append(results, spec.Result{Method: req.Method()})
+		}
+	}
+
+	for _, result := range results {
+		var target **bool
+		switch result.Method {
+		case "test":
+			target = &tests
+		case "example", "session":
+			target = &golden
+		default:
+			continue
+		}
+		if *target == nil {
+			ok := true
+			*target = &ok
+		}
+		**target = **target && result.Met
+	}
+	return tests, golden
+}
+
+func firstLine(s string) string {
+	for i, c := range s {
+		if c == '\n' {
+			return s[:i]
+		}
+	}
+	return s
+}
@@ -0,0 +1,138 @@
+package eval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Summary aggregates the runs of one model and template.
+type Summary struct {
+	Model    string `json:"model"`
+	Template string `json:"template"`
+	Runs     int    `json:"runs"`
+	// Build, Tests and Golden map k to pass@k averaged over the cases.
+	Build      map[int]float64 `json:"build"`
+	Tests      map[int]float64 `json:"tests,omitempty"`
+	Golden     map[int]float64 `json:"golden,omitempty"`
+	AvgRepairs float64         `json:"avgRepairs"`
+	Cost       float64         `json:"cost"`
+}
+
+// Summarize computes pass@k for every model and template.
+func Summarize(s *Suite, runs []Run) []Summary {
+	type key struct{ model, template string }
+	groups := map[key][]Run{}
+	var order []key
+	for _, r := range runs {
+		k := key{r.Model, r.Template}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], r)
+	}
+
+	var summaries []Summary
+	for _, k := range order {
+		group := groups[k]
+		sum := Summary{Model: k.model, Template: k.template, Runs: len(group)}
+		repairs := 0
+		for _, r := range group {
+			repairs += r.Repairs
+			sum.Cost += r.Cost
+		}
+		sum.AvgRepairs = float64(repairs) / float64(len(group))
+		sum.Build = passAtK(group, s.K, func(r Run) *bool { return &r.Build })
+		sum.Tests = passAtK(group, s.K, func(r Run) *bool { return r.Tests })
+		sum.Golden = passAtK(group, s.K, func(r Run) *bool { return r.Golden })
+		summaries = append(summaries, sum)
+	}
+	return summaries
+}
+
+// passAtK estimates pass@k per case with the unbiased estimator
+// 1 - C(n-c, k) / C(n, k) and averages it over the cases that have a
+// result for the metric. Cases with fewer than k runs are skipped.
+func passAtK(runs []Run, ks []int, metric func(Run) *bool) map[int]float64 {
+	type counts struct{ n, c int }
+	byCase := map[string]*counts{}
+	for _, r := range runs {
+		passed := metric(r)
+		if passed == nil {
+			continue
+		}
+		c := byCase[r.Case]
+		if c == nil {
+			c = &counts{}
+			byCase[r.Case] = c
+		}
+		c.n++
+		if *passed {
+			c.c++
+		}
+	}
+	if len(byCase) == 0 {
+		return nil
+	}
+
+	result := map[int]float64{}
+	for _, k := range ks {
+		total, cases := 0.0, 0
+		for _, c := range byCase {
+			if c.n < k {
+				continue
+			}
+			total += estimate(c.n, c.c, k)
+			cases++
+		}
+		if cases > 0 {
+			result[k] = total / float64(cases)
+		}
+	}
+	return result
+}
+
+func estimate(n, c, k int) float64 {
+	if n-c < k {
+		return 1
+	}
+	// 1 - prod_{i=n-c+1}^{n} (1 - k/i)
+	p := 1.0
+	for i := n - c + 1; i <= n; i++ {
+		p *= 1 - float64(k)/float64(i)
+	}
+	return 1 - p
+}
+
+// Markdown renders the summaries as a table.
+func Markdown(s *Suite, summaries []Summary) string {
+	ks := append([]int(nil), s.K...)
+	sort.Ints(ks)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %v\n\n", s.Name)
+	b.WriteString("| model | template | runs |")
+	for _, metric := range []string{"build", "tests", "golden"} {
+		for _, k := range ks {
+			fmt.Fprintf(&b, " %v pass@%d |", metric, k)
+		}
+	}
+	b.WriteString(" avg repairs | cost |\n|---|---|---|")
+	b.WriteString(strings.Repeat("---|", 3*len(ks)+2))
+	b.WriteString("\n")
+
+	for _, sum := range summaries {
+		fmt.Fprintf(&b, "| %v | %v | %d |", sum.Model, sum.Template, sum.Runs)
+		for _, m := range []map[int]float64{sum.Build, sum.Tests, sum.Golden} {
+			for _, k := range ks {
+				if v, ok := m[k]; ok {
+					fmt.Fprintf(&b, " %.2f |", v)
+				} else {
+					b.WriteString(" - |")
+				}
+			}
+		}
+		fmt.Fprintf(&b, " %.2f | $%.4f |\n", sum.AvgRepairs, sum.Cost)
+	}
+	return b.String()
+}
@@ -0,0 +1,173 @@
+// Package fuzz finds input parsing functions in a generated program and
+// runs the native Go fuzz targets chatgpt writes for them.
+package fuzz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// File is where the generated fuzz targets are written.
+const File = "fuzz_test.go"
+
+// Candidate is a top level function that takes a string or []byte.
+type Candidate struct {
+	Name      string
+	Signature string
+}
+
+// Crash is a failing input found by a fuzz target.
+type Crash struct {
+	Target string
+	// Reproducer is the corpus file go test wrote for the failing input.
+	Reproducer string
+	// Input is the failing input as recorded in the corpus file.
+	Input  string
+	Output string
+}
+
+func (c Crash) String() string {
+	return fmt.Sprintf("%v failed on input %v (reproduce with go test -run=%v/%v)", c.Target, c.Input, c.Target, filepath.Base(c.Reproducer))
+}
+
+var failingRe = regexp.MustCompile(`Failing input written to (\S+)`)
+
+// Candidates returns the functions in dir worth fuzzing, parse functions
+// first since those are where the input handling lives.
+func Candidates(dir string) ([]Candidate, error) {
+	fset := token.NewFileSet()
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	var parsers, others []Candidate
+	for _, path := range matches {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %v: %w", path, err)
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !takesInput(fn.Type.Params) {
+				continue
+			}
+			var sig strings.Builder
+			fn.Body = nil
+			printer.Fprint(&sig, fset, fn)
+			candidate := Candidate{Name: fn.Name.Name, Signature: sig.String()}
+			if strings.Contains(strings.ToLower(fn.Name.Name), "parse") {
+				parsers = append(parsers, candidate)
+			} else {
+				others = append(others, candidate)
+			}
+		}
+	}
+	return append(parsers, others...), nil
+}
+
+func takesInput(params *ast.FieldList) bool {
+	for _, field := range params.List {
+		switch t := field.Type.(type) {
+		case *ast.Ident:
+			if t.Name == "string" {
+				return true
+			}
+		case *ast.ArrayType:
+			if ident, ok := t.Elt.(*ast.Ident); ok && t.Len == nil && ident.Name == "byte" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Prompt asks chatgpt for fuzz targets covering the candidates.
+func Prompt(code string, candidates []Candidate) string {
+	var b strings.Builder
+	b.WriteString("I am going to give you a go program in package main. Write a " + File + " file in package main containing native go fuzz targets (func FuzzXxx(f *testing.F)) for the functions listed below. ")
+	b.WriteString("Seed each target with f.Add using typical inputs, and make the targets fail only on panics or on results that break an obvious invariant of the function. ")
+	b.WriteString("Only respond with the contents of " + File + " in raw text and nothing else.\n\nFunctions:\n")
+	for _, c := range candidates {
+		b.WriteString(c.Signature + "\n")
+	}
+	b.WriteString("\nProgram:\n")
+	b.WriteString(code)
+	return b.String()
+}
+
+// Targets lists the fuzz targets defined in the test files of dir.
+func Targets(dir string) ([]string, error) {
+	fset := token.NewFileSet()
+	matches, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []string
+	for _, path := range matches {
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %v: %w", path, err)
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && strings.HasPrefix(fn.Name.Name, "Fuzz") {
+				targets = append(targets, fn.Name.Name)
+			}
+		}
+	}
+	return targets, nil
+}
+
+// Run fuzzes a single target in dir for d with env, nil inheriting
+// makego's. It returns a Crash if the fuzzer found a failing input and an
+// error if the test could not run.
+func Run(dir, target string, d time.Duration, race bool, env []string) (*Crash, error) {
+	// leave time for building and minimizing on top of the fuzz time
+	ctx, cancel := context.WithTimeout(context.Background(), d+2*time.Minute)
+	defer cancel()
+
+	args := []string{"test", "-run=^$", "-fuzz=^" + target + "$", "-fuzztime=" + d.String()}
+	if race {
+		args = append(args, "-race")
+	}
+	cmd := exec.CommandContext(ctx, "go", append(args, ".")...)
+	cmd.Dir = dir
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil, nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return nil, err
+	}
+
+	m := failingRe.FindSubmatch(out)
+	if m == nil {
+		return nil, fmt.Errorf("go test -fuzz=%v: %w\n%s", target, err, out)
+	}
+	crash := &Crash{Target: target, Reproducer: filepath.Join(dir, string(m[1])), Output: string(out)}
+	corpus, err := os.ReadFile(crash.Reproducer)
+	if err != nil {
+		return nil, fmt.Errorf("reading reproducer: %w", err)
+	}
+	// skip the "go test fuzz v1" header line
+	_, input, _ := strings.Cut(string(corpus), "\n")
+	crash.Input = strings.TrimSpace(input)
+	return crash, nil
+}
@@ -0,0 +1,98 @@
+// Package gofix cleans up Go source returned by chatgpt so that it can be
+// written straight into a main.go file and built.
+package gofix
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+var (
+	fenceRe  = regexp.MustCompile("(?s)```[a-zA-Z]*\\s*\n(.*?)```")
+	headerRe = regexp.MustCompile(`^//\s*[\w./-]+\.go\s*$`)
+)
+
+// Extract pulls the Go program out of a chatgpt response. It prefers the
+// first fenced code block, drops anything before the package clause and,
+// if the rest does not parse, any prose after the last closing brace.
+func Extract(response string) string {
+	if m := fenceRe.FindStringSubmatch(response); m != nil {
+		response = m[1]
+	}
+
+	lines := strings.Split(response, "\n")
+	start, found := 0, false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "package ") {
+			found = true
+			break
+		}
+		// keep comments that directly precede the package clause
+		if !strings.HasPrefix(trimmed, "//") && trimmed != "" {
+			start = i + 1
+		}
+	}
+	if !found {
+		start = 0
+	}
+
+	code := strings.Join(lines[start:], "\n")
+	if _, err := parser.ParseFile(token.NewFileSet(), "main.go", code, parser.ParseComments); err == nil {
+		return code
+	}
+
+	// prose after the code: cut after the last top level closing brace
+	for i := len(lines) - 1; i > start; i-- {
+		trimmed := strings.TrimRight(lines[i], " \t\r")
+		if strings.HasPrefix(trimmed, "}") || trimmed == ")" {
+			return strings.Join(lines[start:i+1], "\n") + "\n"
+		}
+	}
+	return code
+}
+
+// Normalize parses src, removes stray file name headers and trailing
+// commentary, fixes the import list and formats the result with go/format.
+func Normalize(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing generated code: %w", err)
+	}
+
+	stripComments(file)
+	out, err := FixImports(fset, file)
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return out, nil
+}
+
+// stripComments drops "// main.go" style headers above the package clause
+// and any comments that trail the last declaration.
+func stripComments(file *ast.File) {
+	var last token.Pos
+	if n := len(file.Decls); n > 0 {
+		last = file.Decls[n-1].End()
+	}
+
+	kept := file.Comments[:0]
+	for _, group := range file.Comments {
+		if group.End() < file.Package && headerRe.MatchString(strings.TrimSpace(group.List[0].Text)) && len(group.List) == 1 {
+			if file.Doc == group {
+				file.Doc = nil
+			}
+			continue
+		}
+		if last.IsValid() && group.Pos() > last {
+			continue
+		}
+		kept = append(kept, group)
+	}
+	file.Comments = kept
+}
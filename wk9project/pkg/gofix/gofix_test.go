@@ -0,0 +1,107 @@
+package gofix
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{
+			name:     "fenced block",
+			response: "Sure! Here is the program:\n```go\npackage main\n\nfunc main() {}\n```\nEnjoy.",
+			want:     "package main\n\nfunc main() {}\n",
+		},
+		{
+			name:     "prose before the package clause",
+			response: "Here is the code\n// main.go\npackage main\n\nfunc main() {}\n",
+			want:     "// main.go\npackage main\n\nfunc main() {}\n",
+		},
+		{
+			name:     "prose after the code",
+			response: "package main\n\nfunc main() {\n}\n\nThis program prints nothing.",
+			want:     "package main\n\nfunc main() {\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Extract(tt.response); got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "missing and unused imports",
+			src:  "package main\nimport \"os\"\nfunc main() { fmt.Println(strings.ToUpper(\"hi\")) }\n",
+			want: "package main\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n\nfunc main() { fmt.Println(strings.ToUpper(\"hi\")) }\n",
+		},
+		{
+			name: "third party imports are kept",
+			src:  "package main\nimport \"github.com/x/y\"\nfunc main() { fmt.Println() }\n",
+			want: "package main\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/x/y\"\n)\n\nfunc main() { fmt.Println() }\n",
+		},
+		{
+			name: "ioutil replaced",
+			src:  "package main\nimport \"io/ioutil\"\nfunc main() { ioutil.ReadFile(\"x\"); ioutil.TempDir(\"\", \"\") }\n",
+			want: "package main\n\nimport (\n\t\"os\"\n)\n\nfunc main() { os.ReadFile(\"x\"); os.MkdirTemp(\"\", \"\") }\n",
+		},
+		{
+			name: "local names are not packages",
+			src:  "package main\ntype T struct{ n int }\nfunc main() { var fmt T; _ = fmt.n }\n",
+			want: "package main\n\ntype T struct{ n int }\n\nfunc main() { var fmt T; _ = fmt.n }\n",
+		},
+		{
+			name: "file header and trailing comments dropped",
+			src:  "// main.go\npackage main\n\nfunc main() {}\n\n// This prints nothing.\n",
+			want: "package main\n\nfunc main() {}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize([]byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Normalize() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeError(t *testing.T) {
+	_, err := Normalize([]byte("package main\nfunc main() {"))
+	if err == nil || !strings.HasPrefix(err.Error(), "parsing generated code") {
+		t.Errorf("Normalize() = %v, want a parse error", err)
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"fmt", "fmt"},
+		{"math/rand", "rand"},
+		{"math/rand/v2", "rand"},
+		{"gopkg.in/yaml.v3", "yaml"},
+		{"github.com/x/y/v10", "y"},
+		{"github.com/x/vendor", "vendor"},
+	}
+	for _, tt := range tests {
+		if got := packageName(tt.path); got != tt.want {
+			t.Errorf("packageName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package gofix
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stdlib maps package names to the standard library import path chatgpt
+// most likely meant. Ambiguous names (rand, template) pick the common one.
+var stdlib = map[string]string{
+	"atomic":   "sync/atomic",
+	"bufio":    "bufio",
+	"bytes":    "bytes",
+	"context":  "context",
+	"csv":      "encoding/csv",
+	"errors":   "errors",
+	"exec":     "os/exec",
+	"filepath": "path/filepath",
+	"flag":     "flag",
+	"fmt":      "fmt",
+	"heap":     "container/heap",
+	"html":     "html",
+	"http":     "net/http",
+	"io":       "io",
+	"ioutil":   "io/ioutil",
+	"json":     "encoding/json",
+	"list":     "container/list",
+	"log":      "log",
+	"maps":     "maps",
+	"math":     "math",
+	"md5":      "crypto/md5",
+	"net":      "net",
+	"os":       "os",
+	"path":     "path",
+	"rand":     "math/rand",
+	"reflect":  "reflect",
+	"regexp":   "regexp",
+	"sha256":   "crypto/sha256",
+	"signal":   "os/signal",
+	"slices":   "slices",
+	"slog":     "log/slog",
+	"sort":     "sort",
+	"strconv":  "strconv",
+	"strings":  "strings",
+	"sync":     "sync",
+	"template": "text/template",
+	"testing":  "testing",
+	"time":     "time",
+	"unicode":  "unicode",
+	"url":      "net/url",
+	"utf8":     "unicode/utf8",
+	"xml":      "encoding/xml",
+}
+
+// ioutilMoves lists the io/ioutil functions that moved to io and os with
+// the same signature.
+var ioutilMoves = map[string]string{
+	"Discard":   "io",
+	"NopCloser": "io",
+	"ReadAll":   "io",
+	"ReadFile":  "os",
+	"WriteFile": "os",
+	"TempDir":   "os.MkdirTemp",
+	"TempFile":  "os.CreateTemp",
+}
+
+// isStdlib reports whether an import path belongs to the standard library.
+func isStdlib(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// packageName guesses the name a package is used by from its import path:
+// the last element, skipping a major version suffix like math/rand/v2 and
+// dropping gopkg.in's like gopkg.in/yaml.v3.
+func packageName(importPath string) string {
+	name := path.Base(importPath)
+	if isMajorVersion(name) && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+	if strings.HasPrefix(importPath, "gopkg.in/") {
+		if base, version, ok := strings.Cut(name, "."); ok && isMajorVersion(version) {
+			name = base
+		}
+	}
+	return name
+}
+
+// isMajorVersion reports whether s is a version element like v2.
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
+
+// FixImports adds standard library imports for package names that are used
+// but not imported and drops standard library imports that are never used.
+// Third party imports are left alone for go mod tidy and the build to judge.
+// The rewritten file is returned formatted.
+func FixImports(fset *token.FileSet, file *ast.File) ([]byte, error) {
+	replaceIoutil(file)
+	used := usedPackages(file)
+
+	imported := map[string]bool{}
+	var std, other []string
+	for _, imp := range file.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		name := packageName(importPath)
+		line := imp.Path.Value
+		if imp.Name != nil {
+			name = imp.Name.Name
+			line = name + " " + line
+		}
+		imported[name] = true
+		switch {
+		case !isStdlib(importPath):
+			other = append(other, line)
+		case name == "_" || name == "." || used[name]:
+			std = append(std, line)
+		}
+	}
+	for name := range used {
+		if importPath, ok := stdlib[name]; ok && !imported[name] {
+			std = append(std, strconv.Quote(importPath))
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+
+	// print the file without its imports, then splice in a fresh block
+	var decls []ast.Decl
+	var dropped []ast.Node
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			dropped = append(dropped, gen)
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	file.Decls = decls
+	kept := file.Comments[:0]
+	for _, group := range file.Comments {
+		if !within(group, dropped) {
+			kept = append(kept, group)
+		}
+	}
+	file.Comments = kept
+
+	var body bytes.Buffer
+	if err := format.Node(&body, fset, file); err != nil {
+		return nil, err
+	}
+
+	var block strings.Builder
+	if len(std)+len(other) > 0 {
+		block.WriteString("\nimport (\n")
+		for _, line := range std {
+			block.WriteString("\t" + line + "\n")
+		}
+		if len(std) > 0 && len(other) > 0 {
+			block.WriteString("\n")
+		}
+		for _, line := range other {
+			block.WriteString("\t" + line + "\n")
+		}
+		block.WriteString(")\n")
+	}
+
+	out := body.String()
+	clause := "package " + file.Name.Name + "\n"
+	i := strings.Index(out, clause)
+	if i < 0 {
+		return nil, fmt.Errorf("package clause not found")
+	}
+	i += len(clause)
+	return format.Source([]byte(out[:i] + block.String() + out[i:]))
+}
+
+// within reports whether a comment group lies inside any of the nodes.
+func within(group *ast.CommentGroup, nodes []ast.Node) bool {
+	for _, n := range nodes {
+		if group.Pos() >= n.Pos() && group.End() <= n.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// usedPackages returns the names used as the left side of a selector that
+// do not resolve to anything declared in the file, i.e. package names.
+func usedPackages(file *ast.File) map[string]bool {
+	unresolved := map[*ast.Ident]bool{}
+	for _, ident := range file.Unresolved {
+		unresolved[ident] = true
+	}
+
+	used := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && unresolved[ident] {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	return used
+}
+
+// replaceIoutil rewrites deprecated io/ioutil calls to their io and os
+// replacements so the ioutil import can be dropped.
+func replaceIoutil(file *ast.File) {
+	unresolved := map[*ast.Ident]bool{}
+	for _, ident := range file.Unresolved {
+		unresolved[ident] = true
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok || ident.Name != "ioutil" || !unresolved[ident] {
+			return true
+		}
+		if target, ok := ioutilMoves[sel.Sel.Name]; ok {
+			pkg, name, renamed := strings.Cut(target, ".")
+			ident.Name = pkg
+			if renamed {
+				sel.Sel.Name = name
+			}
+		}
+		return true
+	})
+}
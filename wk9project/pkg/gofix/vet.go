@@ -0,0 +1,30 @@
+package gofix
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// Vet runs go vet in dir and returns its findings one per line. A failing
+// vet is not an error; only being unable to run it is.
+func Vet(dir string) ([]string, error) {
+	cmd := exec.Command("go", "vet", "./...")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, err
+	}
+
+	var findings []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		// skip package headers like "# crawler"
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		findings = append(findings, line)
+	}
+	return findings, nil
+}
@@ -0,0 +1,172 @@
+// Package history records every generation, repair and edit of a project
+// as a git commit so iterations can be reviewed and bad ones undone. A
+// project gets its own repository unless it is inside one already, in
+// which case only the project directory is committed.
+package history
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Prefix starts the subject of every commit makego makes.
+const Prefix = "makego: "
+
+// undoTrailer names the commit an undo commit reverts.
+const undoTrailer = "Makego-Undoes: "
+
+// Init prepares dir for commits. A repository is created unless dir is
+// already inside one. ignore is added to the project's .gitignore, which
+// is only written if the project does not have one.
+func Init(dir string, ignore ...string) error {
+	if !inWorkTree(dir) {
+		if _, err := git(dir, "init", "-q"); err != nil {
+			return err
+		}
+	}
+	path := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(path); err == nil || len(ignore) == 0 {
+		return nil
+	}
+	return os.WriteFile(path, []byte(strings.Join(ignore, "\n")+"\n"), 0644)
+}
+
+func inWorkTree(dir string) bool {
+	out, err := git(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// Commit commits everything in dir, and nothing outside it, with the
+// subject prefixed by Prefix. It reports false when there was nothing to
+// commit.
+func Commit(dir, subject, body string) (bool, error) {
+	if _, err := git(dir, "add", "-A", "--", "."); err != nil {
+		return false, err
+	}
+	if _, err := git(dir, "diff", "--cached", "--quiet", "--", "."); err == nil {
+		return false, nil
+	}
+	message := Prefix + subject
+	if body != "" {
+		message += "\n\n" + body
+	}
+	if _, err := git(dir, "commit", "-q", "-m", message, "--", "."); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Summary shortens text to its first line of at most 60 characters for a
+// commit subject.
+func Summary(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if r := []rune(line); len(r) > 60 {
+		line = strings.TrimSpace(string(r[:57])) + "..."
+	}
+	return line
+}
+
+type commit struct {
+	hash    string
+	subject string
+	undoes  string
+}
+
+// log returns the commits touching dir, newest first.
+func log(dir string) ([]commit, error) {
+	out, err := git(dir, "log", "--format=%H%x00%s%x00%b%x1e", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	var commits []commit
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(record), "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		c := commit{hash: fields[0], subject: fields[1]}
+		scanner := bufio.NewScanner(strings.NewReader(fields[2]))
+		for scanner.Scan() {
+			if hash, ok := strings.CutPrefix(scanner.Text(), undoTrailer); ok {
+				c.undoes = strings.TrimSpace(hash)
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// Undo restores dir to how it was before its latest makego iteration that
+// has not been undone yet and commits that. Undoing again goes back one
+// more iteration. The first generation cannot be undone. It returns the
+// subject of the undone commit.
+func Undo(dir string) (string, error) {
+	if !inWorkTree(dir) {
+		return "", fmt.Errorf("%v is not in a git repository, generate it with -git", dir)
+	}
+	if out, err := git(dir, "status", "--porcelain", "--", "."); err != nil {
+		return "", err
+	} else if out != "" {
+		return "", errors.New("the project has uncommitted changes, commit or discard them first")
+	}
+
+	commits, err := log(dir)
+	if err != nil {
+		return "", err
+	}
+	undone := map[string]bool{}
+	var live []commit
+	for _, c := range commits {
+		switch {
+		case c.undoes != "":
+			undone[c.undoes] = true
+		case undone[c.hash]:
+		case strings.HasPrefix(c.subject, Prefix):
+			live = append(live, c)
+		}
+	}
+	if len(live) == 0 {
+		return "", errors.New("no makego commits to undo")
+	}
+	if len(live) == 1 {
+		return "", fmt.Errorf("only the first generation is left (%v); delete the project instead", live[0].subject)
+	}
+	target, previous := live[0], live[1]
+
+	if _, err := git(dir, "rm", "-r", "-q", "--", "."); err != nil {
+		return "", err
+	}
+	if _, err := git(dir, "checkout", previous.hash, "--", "."); err != nil {
+		return "", err
+	}
+	subject := "undo " + strings.TrimPrefix(target.subject, Prefix)
+	body := "Restores the project to " + previous.hash[:12] + " (" + previous.subject + ").\n\n" + undoTrailer + target.hash
+	committed, err := Commit(dir, subject, body)
+	if err != nil {
+		return "", err
+	}
+	if !committed {
+		return "", fmt.Errorf("undoing %v changes nothing", target.subject)
+	}
+	return target.subject, nil
+}
+
+// git runs a git command in dir and returns its output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("git %v: %w\n%s", args[0], err, exitErr.Stderr)
+		}
+		return "", fmt.Errorf("git %v: %w", args[0], err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,97 @@
+// Package llm creates the model clients makego talks to. The "chatgpt"
+// provider is the wk8 client, "openai" calls the chat completions API
+// directly so the model and temperature can be chosen.
+package llm
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jeremycruzz/msds301-wk8/pkg/chatgpt"
+)
+
+// Asker sends a prompt and returns the model's response.
+type Asker interface {
+	AskCustom(prompt string) (string, error)
+}
+
+// Metered is an Asker that knows how many tokens it has used.
+type Metered interface {
+	Asker
+	Tokens() (prompt, completion int)
+}
+
+// Config selects a provider and its settings.
+type Config struct {
+	Provider    string  `json:"provider"`
+	Model       string  `json:"model,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+}
+
+func (c Config) String() string {
+	s := c.Provider
+	if c.Model != "" {
+		s += "/" + c.Model
+	}
+	if c.Temperature != 0 {
+		s += fmt.Sprintf("@%g", c.Temperature)
+	}
+	return s
+}
+
+// New creates a client for cfg.
+func New(cfg Config, apiKey string) (Asker, error) {
+	switch cfg.Provider {
+	case "", "chatgpt":
+		if cfg.Model != "" || cfg.Temperature != 0 {
+			return nil, fmt.Errorf("the chatgpt provider uses its own model settings, use the openai provider to pick a model or temperature")
+		}
+		return chatgpt.New(apiKey), nil
+	case "openai":
+		return NewOpenAI(apiKey, cfg.Model, cfg.Temperature), nil
+	}
+	return nil, fmt.Errorf("unknown provider %q", cfg.Provider)
+}
+
+// Meter returns client as a Metered, estimating tokens at four characters
+// each when the client cannot report them itself.
+func Meter(client Asker) Metered {
+	if m, ok := client.(Metered); ok {
+		return m
+	}
+	return &estimator{client: client}
+}
+
+type estimator struct {
+	client Asker
+
+	mu         sync.Mutex
+	prompt     int
+	completion int
+}
+
+func (e *estimator) AskCustom(prompt string) (string, error) {
+	response, err := e.client.AskCustom(prompt)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.prompt += (len(prompt) + 3) / 4
+	e.completion += (len(response) + 3) / 4
+	return response, err
+}
+
+func (e *estimator) Tokens() (int, int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.prompt, e.completion
+}
+
+// Price is the cost per thousand tokens.
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Cost returns the cost of the given token counts.
+func (p Price) Cost(prompt, completion int) float64 {
+	return float64(prompt)/1000*p.Input + float64(completion)/1000*p.Output
+}
@@ -0,0 +1,110 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultModel is used by the openai provider when no model is set.
+const DefaultModel = "gpt-3.5-turbo"
+
+const completionsURL = "https://api.openai.com/v1/chat/completions"
+
+// OpenAI calls the chat completions API with a chosen model and
+// temperature and keeps count of the tokens used.
+type OpenAI struct {
+	apiKey      string
+	model       string
+	temperature float64
+	http        *http.Client
+
+	mu         sync.Mutex
+	prompt     int
+	completion int
+}
+
+// NewOpenAI creates an OpenAI client. An empty model uses DefaultModel.
+func NewOpenAI(apiKey, model string, temperature float64) *OpenAI {
+	if model == "" {
+		model = DefaultModel
+	}
+	return &OpenAI{
+		apiKey:      apiKey,
+		model:       model,
+		temperature: temperature,
+		http:        &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// AskCustom sends prompt as a single user message.
+func (c *OpenAI) AskCustom(prompt string) (string, error) {
+	body, err := json.Marshal(struct {
+		Model       string    `json:"model"`
+		Temperature float64   `json:"temperature"`
+		Messages    []message `json:"messages"`
+	}{c.model, c.temperature, []message{{"user", prompt}}})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, completionsURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Choices []struct {
+			Message message `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decoding response (%v): %w", resp.Status, err)
+	}
+	if out.Error != nil {
+		return "", fmt.Errorf("openai: %v", out.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("openai: %v", resp.Status)
+	}
+	if len(out.Choices) == 0 {
+		return "", errors.New("openai: no choices in response")
+	}
+
+	c.mu.Lock()
+	c.prompt += out.Usage.PromptTokens
+	c.completion += out.Usage.CompletionTokens
+	c.mu.Unlock()
+	return out.Choices[0].Message.Content, nil
+}
+
+// Tokens returns the prompt and completion tokens used so far.
+func (c *OpenAI) Tokens() (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.prompt, c.completion
+}
@@ -0,0 +1,82 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/fuzz"
+)
+
+// Race builds dir with the race detector and runs any tests under it.
+func (g *Generator) Race(dir string) {
+	g.logf("Building with race detector...\n")
+	if err := Go(dir, "build", "-race", "-o", os.DevNull); err != nil {
+		g.logf("Error building with -race: %v\n", err)
+		return
+	}
+
+	tests, _ := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if len(tests) == 0 {
+		g.logf("No tests to run with -race.\n")
+		return
+	}
+	g.logf("Running tests with race detector...\n")
+	if err := goEnv(dir, g.Env, "test", "-race", "./..."); err != nil {
+		g.logf("Race detector found problems: %v\n", err)
+		return
+	}
+	g.logf("No races found.\n")
+}
+
+// Fuzz asks chatgpt for fuzz targets for the input parsing functions in
+// dir and runs each of them for d.
+func (g *Generator) Fuzz(dir string, d time.Duration, race bool) {
+	candidates, err := fuzz.Candidates(dir)
+	if err != nil {
+		g.logf("Error finding functions to fuzz: %v\n", err)
+		return
+	}
+	if len(candidates) == 0 {
+		g.logf("No functions taking string or []byte input to fuzz.\n")
+		return
+	}
+
+	code, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		g.logf("Error reading main.go: %v\n", err)
+		return
+	}
+
+	g.logf("Asking chatgpt for fuzz targets for %d function(s)...\n", len(candidates))
+	response, err := g.Client.AskCustom(fuzz.Prompt(string(code), candidates))
+	if err != nil {
+		g.logf("Error with chatgpt: %v\n", err)
+		return
+	}
+	if err := g.WriteCode(dir, fuzz.File, response); err != nil {
+		g.logf("Error writing fuzz targets: %v\n", err)
+		return
+	}
+
+	targets, err := fuzz.Targets(dir)
+	if err != nil {
+		g.logf("Error listing fuzz targets: %v\n", err)
+		return
+	}
+
+	crashes := 0
+	for _, target := range targets {
+		g.logf("Fuzzing %v for %v...\n", target, d)
+		crash, err := fuzz.Run(dir, target, d, race, g.Env)
+		if err != nil {
+			g.logf("Error fuzzing: %v\n", err)
+			continue
+		}
+		if crash != nil {
+			crashes++
+			g.logf("  crash: %v\n", crash)
+		}
+	}
+	g.logf("Fuzzing complete: %d target(s), %d crash(es).\n", len(targets), crashes)
+}
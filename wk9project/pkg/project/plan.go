@@ -0,0 +1,79 @@
+package project
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// outlinePreamble asks for a design instead of code.
+const outlinePreamble = "Before writing any code, outline the design of a go program contained in a single main.go file. List the types, the functions with their responsibilities, how input and output work and how each feature asked for is handled. Respond with a short bullet outline and no code. The program: "
+
+// Outline asks chatgpt for a design outline of the program. When previous
+// is set, the outline is revised according to feedback.
+func (g *Generator) Outline(prompt, previous, feedback string) (string, error) {
+	request := outlinePreamble + prompt
+	if previous != "" {
+		request += "\n\nHere is your previous outline:\n" + previous + "\n\nRevise it according to this feedback: " + feedback
+	}
+	g.logf("Asking chatgpt for a design outline...\n")
+	outline, err := g.Client.AskCustom(request)
+	if err != nil {
+		return "", fmt.Errorf("asking chatgpt: %w", err)
+	}
+	return strings.TrimSpace(outline), nil
+}
+
+// WithOutline adds an approved design outline to the program description.
+func WithOutline(prompt, outline string) string {
+	return prompt + "\n\nFollow this design:\n" + outline
+}
+
+// DryRun writes what Create would do for opts without asking the model or
+// touching the disk.
+func DryRun(w io.Writer, opts Options) error {
+	prompt, err := opts.render()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Target directory: %v", opts.Dir())
+	if _, err := os.Stat(opts.Dir()); err == nil {
+		fmt.Fprintf(w, " (already exists, generation would fail)")
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Prompt:\n%v\n\n", prompt)
+	fmt.Fprintf(w, "Commands:\n")
+	fmt.Fprintf(w, "  mkdir %v\n", opts.Dir())
+	fmt.Fprintf(w, "  go mod init %v\n", opts.Name)
+	if opts.GoVersion != "" {
+		fmt.Fprintf(w, "  go mod edit -go=%v\n", opts.GoVersion)
+	}
+	if opts.Toolchain != "" {
+		fmt.Fprintf(w, "  go mod edit -toolchain=%v\n", opts.Toolchain)
+	}
+	for _, mod := range sortedKeys(opts.Replaces) {
+		fmt.Fprintf(w, "  go mod edit -replace=%v=%v\n", mod, opts.Replaces[mod])
+	}
+	fmt.Fprintf(w, "  (ask the model, write main.go)\n")
+	fmt.Fprintf(w, "  go mod tidy\n")
+	fmt.Fprintf(w, "  go vet ./...\n")
+	fmt.Fprintf(w, "  go build\n")
+	if toolchain := opts.verifyToolchain(); toolchain != "" {
+		fmt.Fprintf(w, "  GOTOOLCHAIN=%v go vet ./...\n", toolchain)
+		fmt.Fprintf(w, "  GOTOOLCHAIN=%v go build -o %v\n", toolchain, os.DevNull)
+	}
+	if opts.Repairs > 0 {
+		fmt.Fprintf(w, "  (on build failure, up to %d repair round(s) repeating tidy, vet and build)\n", opts.Repairs)
+	}
+	if opts.Race {
+		fmt.Fprintf(w, "  go build -race -o %v\n", os.DevNull)
+		fmt.Fprintf(w, "  go test -race ./... (if there are tests)\n")
+	}
+	if opts.FuzzTime > 0 {
+		fmt.Fprintf(w, "  (ask the model for fuzz targets, write fuzz_test.go)\n")
+		fmt.Fprintf(w, "  go test -run=^$ -fuzz=^FuzzXxx$ -fuzztime=%v . (for each target)\n", opts.FuzzTime)
+	}
+	return nil
+}
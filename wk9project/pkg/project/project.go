@@ -0,0 +1,353 @@
+// Package project runs the makego pipeline: create a module, ask chatgpt
+// for main.go, clean the code up, then tidy, vet and build it. Progress is
+// written to a log writer so the CLI and the server can share it.
+package project
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/gofix"
+	"github.com/jeremycruzz/msds301-wk9/pkg/history"
+)
+
+// CodePreamble asks chatgpt for nothing but the contents of main.go.
+const CodePreamble = "I am going to ask you to write a go program for me all contained within a main.go file. Only respond with the contents of this main.go file in raw text and nothing else. I'm going to paste your response directly into a go file. Do not include anything before or after the code including comments explaining the code. " +
+	"If the program uses random numbers, seed them from the " + SeedEnv + " environment variable when it is set, so a run can be replayed. "
+
+// SeedEnv is the environment variable generated programs seed their
+// random numbers from when it is set. Replays set it so that programs
+// with random output, like card games, print the same thing every run.
+const SeedEnv = "MAKEGO_SEED"
+
+// Asker is the part of the chatgpt client makego needs.
+type Asker interface {
+	AskCustom(prompt string) (string, error)
+}
+
+// Options describe a program to generate.
+type Options struct {
+	Name   string
+	Prompt string
+	// Root is the directory the project directory is created in.
+	Root string
+	// Race builds and tests with the race detector.
+	Race bool
+	// FuzzTime runs chatgpt written fuzz targets for this long each, 0 skips.
+	FuzzTime time.Duration
+	// Template wraps Prompt, see RenderPrompt. Empty is the default.
+	Template string
+	// Repairs is how many times a failed build is sent back to chatgpt.
+	Repairs int
+	// Context describes existing packages to reuse and is added after the
+	// rendered prompt, see retrieve.Prompt.
+	Context string
+	// Replaces maps the module paths Context imports from to their local
+	// directories. Each gets a replace directive in the new go.mod.
+	Replaces map[string]string
+	// GoVersion, like 1.21, is written as the go directive and stated in
+	// the prompt, and the build is verified with that release unless
+	// Toolchain is set. Empty keeps the installed version.
+	GoVersion string
+	// Toolchain, like go1.21.5, is written as the toolchain line and the
+	// build is verified with it.
+	Toolchain string
+}
+
+// Result describes a generated project.
+type Result struct {
+	Dir string
+	// Repairs is the number of repair rounds the build needed.
+	Repairs int
+}
+
+// render fills the template with the prompt and adds the context.
+func (o Options) render() (string, error) {
+	prompt, err := RenderPrompt(o.Template, o.Prompt)
+	if err != nil {
+		return "", err
+	}
+	if o.GoVersion != "" {
+		note, err := versionNote(o.GoVersion)
+		if err != nil {
+			return "", err
+		}
+		prompt += "\n\n" + note
+	}
+	if o.Context != "" {
+		prompt += "\n\n" + o.Context
+	}
+	return prompt, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Dir is the directory the project is generated in.
+func (o Options) Dir() string {
+	return filepath.Join(o.Root, o.Name)
+}
+
+// Generator creates and edits projects.
+type Generator struct {
+	Client Asker
+	Log    io.Writer
+	// Git commits every generation, repair and edit, see package history.
+	Git bool
+	// Env is the environment the program's tests and fuzz targets run
+	// with. Nil inherits makego's.
+	Env []string
+}
+
+func (g *Generator) logf(format string, args ...any) {
+	fmt.Fprintf(g.Log, format, args...)
+}
+
+// Create generates a new project from opts. The result is returned even
+// on error as long as the project directory was created.
+func (g *Generator) Create(opts Options) (*Result, error) {
+	res := &Result{Dir: opts.Dir()}
+	prompt, err := opts.render()
+	if err != nil {
+		return nil, err
+	}
+
+	// create new directory
+	g.logf("Creating directory: %v...\n", res.Dir)
+	if err := os.Mkdir(res.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating directory: %w", err)
+	}
+
+	// init go mod
+	g.logf("Creating go module: %v...\n", opts.Name)
+	if err := Go(res.Dir, "mod", "init", opts.Name); err != nil {
+		return res, fmt.Errorf("initializing go module: %w", err)
+	}
+	if err := setVersion(res.Dir, opts.GoVersion, opts.Toolchain); err != nil {
+		return res, fmt.Errorf("setting the Go version: %w", err)
+	}
+	for _, mod := range sortedKeys(opts.Replaces) {
+		g.logf("Using local module: %v => %v\n", mod, opts.Replaces[mod])
+		if err := Go(res.Dir, "mod", "edit", "-replace="+mod+"="+opts.Replaces[mod]); err != nil {
+			return res, fmt.Errorf("adding replace directive: %w", err)
+		}
+	}
+
+	// ask chat gpt for code
+	g.logf("Asking chatgpt: \n%v\n", prompt)
+	code, err := g.Client.AskCustom(prompt)
+	if err != nil {
+		return res, fmt.Errorf("asking chatgpt: %w", err)
+	}
+
+	if err := g.WriteCode(res.Dir, "main.go", code); err != nil {
+		return res, err
+	}
+	if g.Git {
+		g.logf("Initializing git repository...\n")
+		if err := history.Init(res.Dir, "/"+opts.Name, "/"+opts.Name+".exe"); err != nil {
+			return res, fmt.Errorf("initializing git: %w", err)
+		}
+	}
+	subject := "generate " + opts.Name
+	for {
+		err := g.Build(res.Dir)
+		if toolchain := opts.verifyToolchain(); err == nil && toolchain != "" {
+			err = g.Verify(res.Dir, toolchain)
+		}
+		g.commit(res.Dir, subject, "Prompt: "+opts.Prompt, err)
+		if err == nil {
+			break
+		}
+		if res.Repairs >= opts.Repairs {
+			return res, err
+		}
+		res.Repairs++
+		subject = fmt.Sprintf("repair %v, round %d of %d", opts.Name, res.Repairs, opts.Repairs)
+		g.logf("Build failed, repair round %d of %d...\n", res.Repairs, opts.Repairs)
+		if err := g.repair(res.Dir, err, opts.Context); err != nil {
+			return res, err
+		}
+	}
+
+	if opts.Race {
+		g.Race(res.Dir)
+	}
+	if opts.FuzzTime > 0 {
+		g.Fuzz(res.Dir, opts.FuzzTime, opts.Race)
+	}
+
+	g.logf("Project setup and build complete.\n")
+	return res, nil
+}
+
+// repair sends the code and the build error back to chatgpt and writes
+// the corrected code, along with the context of packages to reuse.
+func (g *Generator) repair(dir string, buildErr error, context string) error {
+	code, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		return fmt.Errorf("reading main.go: %w", err)
+	}
+
+	prompt := CodePreamble + "This program does not build. Fix it and respond with the complete corrected main.go.\n\nBuild output:\n" + buildErr.Error() + "\n\nProgram:\n" + string(code)
+	if context != "" {
+		prompt += "\n\n" + context
+	}
+	response, err := g.Client.AskCustom(prompt)
+	if err != nil {
+		return fmt.Errorf("asking chatgpt: %w", err)
+	}
+	return g.WriteCode(dir, "main.go", response)
+}
+
+// Edit asks chatgpt to change the program in dir as instructed and
+// rebuilds it.
+func (g *Generator) Edit(dir, instruction string) error {
+	code, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		return fmt.Errorf("reading main.go: %w", err)
+	}
+
+	prompt := CodePreamble + "Here is the current program. Change it as follows and respond with the complete updated main.go: " + instruction + "\n\nProgram:\n" + string(code)
+	g.logf("Asking chatgpt to edit: %v\n", instruction)
+	response, err := g.Client.AskCustom(prompt)
+	if err != nil {
+		return fmt.Errorf("asking chatgpt: %w", err)
+	}
+
+	if err := g.WriteCode(dir, "main.go", response); err != nil {
+		return err
+	}
+	err = g.Build(dir)
+	g.commit(dir, "edit: "+history.Summary(instruction), "Instruction: "+instruction, err)
+	if err != nil {
+		return err
+	}
+	g.logf("Edit and build complete.\n")
+	return nil
+}
+
+// commit records an attempt and its build result when Git is set. Commit
+// failures are logged; they do not stop the generation.
+func (g *Generator) commit(dir, subject, body string, buildErr error) {
+	if !g.Git {
+		return
+	}
+	result := "Build: ok"
+	if buildErr != nil {
+		subject += " (build failed)"
+		result = "Build failed:\n" + lastLines(buildErr.Error(), 10)
+	}
+	if _, err := history.Commit(dir, subject, body+"\n\n"+result); err != nil {
+		g.logf("Error committing: %v\n", err)
+		return
+	}
+	g.logf("Committed: %v%v\n", history.Prefix, subject)
+}
+
+// lastLines returns at most the last n lines of s.
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteCode strips fences and prose from a chatgpt response, fixes the
+// imports, gofmts it and writes it to file in dir.
+func (g *Generator) WriteCode(dir, file, response string) error {
+	code := gofix.Extract(response)
+	src, err := gofix.Normalize([]byte(code))
+	if err != nil {
+		g.logf("Could not normalize code, writing it as is: %v\n", err)
+		src = []byte(code)
+	}
+
+	g.logf("Writing to %v...\n", file)
+	if err := os.WriteFile(filepath.Join(dir, file), src, 0644); err != nil {
+		return fmt.Errorf("writing Go code to file: %w", err)
+	}
+	return nil
+}
+
+// Build tidies, vets and builds the project in dir. Vet findings are
+// logged and written to vet.txt but do not stop the build.
+func (g *Generator) Build(dir string) error {
+	// tidy module
+	g.logf("Tidying dependencies...\n")
+	if err := Go(dir, "mod", "tidy"); err != nil {
+		return fmt.Errorf("running go mod tidy: %w", err)
+	}
+
+	// vet before building and keep the findings next to the code
+	g.logf("Vetting code...\n")
+	findings, err := gofix.Vet(dir)
+	if err != nil {
+		return fmt.Errorf("running go vet: %w", err)
+	}
+	for _, finding := range findings {
+		g.logf("  vet: %v\n", finding)
+	}
+	if len(findings) > 0 {
+		err = os.WriteFile(filepath.Join(dir, "vet.txt"), []byte(strings.Join(findings, "\n")+"\n"), 0644)
+		if err != nil {
+			return fmt.Errorf("writing vet findings: %w", err)
+		}
+	}
+
+	// build
+	g.logf("Building...\n")
+	if err := Go(dir, "build"); err != nil {
+		return fmt.Errorf("building the project: %w", err)
+	}
+	return nil
+}
+
+// BuildBinary builds the program in dir into a new temporary directory
+// and returns the binary's path. The caller removes its directory.
+func BuildBinary(dir string) (string, error) {
+	tmp, err := os.MkdirTemp("", "makego-bin")
+	if err != nil {
+		return "", err
+	}
+	bin := filepath.Join(tmp, "program")
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	if err := Go(dir, "build", "-o", bin, "."); err != nil {
+		os.RemoveAll(tmp)
+		return "", fmt.Errorf("building the project: %w", err)
+	}
+	return bin, nil
+}
+
+// Go runs a go subcommand in dir and includes its output in the error.
+func Go(dir string, args ...string) error {
+	return goEnv(dir, nil, args...)
+}
+
+// goEnv is Go with env, nil inheriting makego's.
+func goEnv(dir string, env []string, args ...string) error {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("go %v: %w\n%s", strings.Join(args, " "), err, out)
+	}
+	return nil
+}
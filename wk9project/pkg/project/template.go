@@ -0,0 +1,48 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Templates are the built in prompt templates. {{.Prompt}} is replaced
+// with the program description.
+var Templates = map[string]string{
+	"default":  CodePreamble + "{{.Prompt}}",
+	"detailed": CodePreamble + "Write complete, idiomatic code: handle every error, avoid deprecated functions, split the logic into small functions and implement every feature asked for without leaving placeholders. {{.Prompt}}",
+}
+
+// LoadTemplate returns a built in template by name, or reads a template
+// file when name is not one of them. An empty name is the default.
+func LoadTemplate(name string) (string, error) {
+	if name == "" {
+		name = "default"
+	}
+	if tmpl, ok := Templates[name]; ok {
+		return tmpl, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("template %q is not built in and could not be read: %w", name, err)
+	}
+	return string(data), nil
+}
+
+// RenderPrompt fills tmpl with the program description. An empty tmpl is
+// the default template.
+func RenderPrompt(tmpl, prompt string) (string, error) {
+	if tmpl == "" {
+		tmpl = Templates["default"]
+	}
+	t, err := template.New("prompt").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing prompt template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, struct{ Prompt string }{prompt}); err != nil {
+		return "", fmt.Errorf("rendering prompt template: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,106 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// deprecations are the standard library APIs chatgpt keeps using after
+// they were deprecated, by the Go version that deprecated them.
+var deprecations = []struct {
+	since int
+	api   string
+}{
+	{16, "io/ioutil (use the io and os functions)"},
+	{18, "strings.Title (use golang.org/x/text/cases)"},
+	{20, "rand.Seed (the global source is seeded automatically) and rand.Read (use crypto/rand)"},
+}
+
+// minor returns the minor number of a Go version like 1.21 or go1.21.5.
+func minor(version string) (int, error) {
+	v := strings.TrimPrefix(version, "go")
+	parts := strings.Split(v, ".")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "1" {
+		return 0, fmt.Errorf("invalid Go version %q, want something like 1.21 or 1.21.5", version)
+	}
+	for _, part := range parts[1:] {
+		if _, err := strconv.Atoi(part); err != nil {
+			return 0, fmt.Errorf("invalid Go version %q, want something like 1.21 or 1.21.5", version)
+		}
+	}
+	n, _ := strconv.Atoi(parts[1])
+	return n, nil
+}
+
+// versionNote tells the model which Go version the program must build
+// with and which deprecated APIs to stay away from.
+func versionNote(version string) (string, error) {
+	n, err := minor(version)
+	if err != nil {
+		return "", err
+	}
+	note := fmt.Sprintf("The program must build with Go %v: do not use language features or standard library APIs added after Go %v.", version, version)
+	var avoid []string
+	for _, d := range deprecations {
+		if n >= d.since {
+			avoid = append(avoid, d.api)
+		}
+	}
+	if len(avoid) > 0 {
+		note += " Do not use these deprecated APIs: " + strings.Join(avoid, "; ") + "."
+	}
+	return note, nil
+}
+
+// setVersion writes the go directive and toolchain line of the go.mod in
+// dir. Empty values are left as go mod init wrote them.
+func setVersion(dir, goVersion, toolchain string) error {
+	args := []string{"mod", "edit"}
+	if goVersion != "" {
+		args = append(args, "-go="+goVersion)
+	}
+	if toolchain != "" {
+		args = append(args, "-toolchain="+toolchain)
+	}
+	if len(args) == 2 {
+		return nil
+	}
+	return Go(dir, args...)
+}
+
+// verifyToolchain is the toolchain the build is verified with: the
+// toolchain line when there is one, or else the release the go directive
+// names, like go1.20 for 1.20 and go1.21.0 for 1.21 since toolchains from
+// 1.21 on carry a patch number. It is empty when neither is set.
+func (o Options) verifyToolchain() string {
+	switch {
+	case o.Toolchain != "":
+		return o.Toolchain
+	case o.GoVersion == "":
+		return ""
+	}
+	toolchain := "go" + o.GoVersion
+	if n, err := minor(o.GoVersion); err == nil && n >= 21 && strings.Count(o.GoVersion, ".") == 1 {
+		toolchain += ".0"
+	}
+	return toolchain
+}
+
+// Verify vets and builds the project in dir with the given toolchain,
+// like go1.21.5, which the go command downloads if it is not installed.
+func (g *Generator) Verify(dir, toolchain string) error {
+	g.logf("Verifying build with %v...\n", toolchain)
+	for _, args := range [][]string{{"vet", "./..."}, {"build", "-o", os.DevNull}} {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOTOOLCHAIN="+toolchain)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("go %v with %v: %w\n%s", strings.Join(args, " "), toolchain, err, out)
+		}
+	}
+	return nil
+}
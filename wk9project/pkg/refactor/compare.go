@@ -0,0 +1,261 @@
+package refactor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+)
+
+// runTimeout bounds each run of a recorded session.
+const runTimeout = 10 * time.Second
+
+// seed is the project.SeedEnv value every replay runs with.
+const seed = "1"
+
+// argsPrefix starts a recording line holding the program arguments.
+const argsPrefix = "# args:"
+
+// Recording is stdin recorded from a program session, replayed to compare
+// two builds of the program.
+type Recording struct {
+	Name  string
+	Args  []string
+	Input string
+}
+
+// LoadRecording reads a recording file: the input typed into the program,
+// optionally preceded by a "# args: ..." line.
+func LoadRecording(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recording{Name: filepath.Base(path), Input: string(data)}
+	if first, rest, _ := strings.Cut(r.Input, "\n"); strings.HasPrefix(first, argsPrefix) {
+		r.Args = strings.Fields(strings.TrimPrefix(first, argsPrefix))
+		r.Input = rest
+	}
+	return r, nil
+}
+
+// Record runs bin with args, env and the replay seed, passing in through
+// to the program and its output to out, and saves everything typed to path
+// as a recording. A nil env inherits makego's environment.
+func Record(bin string, args, env []string, in io.Reader, out io.Writer, path string) error {
+	var typed bytes.Buffer
+	if len(args) > 0 {
+		fmt.Fprintf(&typed, "%v %v\n", argsPrefix, strings.Join(args, " "))
+	}
+
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd := exec.Command(bin, args...)
+	cmd.Env = append(env[:len(env):len(env)], project.SeedEnv+"="+seed)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// copy lines until the program exits or input ends
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	lines := make(chan string)
+	go func() {
+		reader := bufio.NewReader(in)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				lines <- line
+			}
+			if err != nil {
+				close(lines)
+				return
+			}
+		}
+	}()
+
+	var runErr error
+loop:
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				stdin.Close()
+				runErr = <-done
+				break loop
+			}
+			typed.WriteString(line)
+			io.WriteString(stdin, line)
+		case runErr = <-done:
+			break loop
+		}
+	}
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return runErr
+	}
+	return os.WriteFile(path, typed.Bytes(), 0644)
+}
+
+// Output is what one run of a recording printed.
+type Output struct {
+	Text     string
+	Exit     int
+	TimedOut bool
+}
+
+// Replay runs bin with the recording as stdin and project.SeedEnv set,
+// so programs that honour it deal the same random numbers every run. A
+// nil env inherits makego's environment.
+func Replay(bin string, r *Recording, env []string) (Output, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	defer cancel()
+
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd := exec.CommandContext(ctx, bin, r.Args...)
+	cmd.Env = append(env[:len(env):len(env)], project.SeedEnv+"="+seed)
+	cmd.Stdin = strings.NewReader(r.Input)
+	out, err := cmd.CombinedOutput()
+	o := Output{Text: string(out), TimedOut: ctx.Err() != nil}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil, o.TimedOut:
+	case errors.As(err, &exitErr):
+		o.Exit = exitErr.ExitCode()
+	default:
+		return o, err
+	}
+	return o, nil
+}
+
+// Comparison is the result of replaying one recording against the old
+// and new program.
+type Comparison struct {
+	Name  string
+	Equal bool
+	// Nondeterministic is set when the old program printed different
+	// output on two runs, even with project.SeedEnv set, so the outputs
+	// cannot be compared.
+	Nondeterministic bool
+	Detail           string
+}
+
+// Compare replays each recording against the old and new binaries. The
+// old binary runs twice to detect programs whose output varies between
+// runs, like ones that shuffle with a time based seed and ignore
+// project.SeedEnv.
+func Compare(oldBin, newBin string, recordings []*Recording, env []string) ([]Comparison, error) {
+	var results []Comparison
+	for _, r := range recordings {
+		c := Comparison{Name: r.Name}
+		before, err := Replay(oldBin, r, env)
+		if err != nil {
+			return nil, fmt.Errorf("%v: running the old program: %w", r.Name, err)
+		}
+		again, err := Replay(oldBin, r, env)
+		if err != nil {
+			return nil, fmt.Errorf("%v: running the old program: %w", r.Name, err)
+		}
+		after, err := Replay(newBin, r, env)
+		if err != nil {
+			return nil, fmt.Errorf("%v: running the new program: %w", r.Name, err)
+		}
+
+		switch {
+		case before.TimedOut:
+			c.Detail = fmt.Sprintf("the old program did not finish within %v; end the recording with input that quits", runTimeout)
+		case before != again:
+			c.Nondeterministic = true
+			c.Detail = "the old program printed different output on two runs: " + difference(before, again)
+		case before != after:
+			c.Detail = difference(before, after)
+		default:
+			c.Equal = true
+		}
+		results = append(results, c)
+	}
+	return results, nil
+}
+
+// difference describes the first difference between two outputs.
+func difference(a, b Output) string {
+	if a.TimedOut != b.TimedOut {
+		return fmt.Sprintf("timed out: %v before, %v after", a.TimedOut, b.TimedOut)
+	}
+	aLines, bLines := strings.Split(a.Text, "\n"), strings.Split(b.Text, "\n")
+	for i := 0; i < len(aLines) || i < len(bLines); i++ {
+		var x, y string
+		if i < len(aLines) {
+			x = aLines[i]
+		}
+		if i < len(bLines) {
+			y = bLines[i]
+		}
+		if x != y {
+			return fmt.Sprintf("line %d: %q before, %q after", i+1, x, y)
+		}
+	}
+	return fmt.Sprintf("exit status %d before, %d after", a.Exit, b.Exit)
+}
+
+// Report formats the comparisons.
+func Report(results []Comparison) string {
+	var b strings.Builder
+	equal := 0
+	for _, c := range results {
+		status := "DIFFERENT"
+		switch {
+		case c.Equal:
+			status = "same"
+			equal++
+		case c.Nondeterministic:
+			status = "unsupported"
+		}
+		fmt.Fprintf(&b, "%v: %v", c.Name, status)
+		if c.Detail != "" {
+			fmt.Fprintf(&b, " (%v)", c.Detail)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%d/%d session(s) behave the same\n", equal, len(results))
+	return b.String()
+}
+
+// Unsupported reports whether any recording could not be compared because
+// the program's output varies between runs.
+func Unsupported(results []Comparison) bool {
+	for _, c := range results {
+		if c.Nondeterministic {
+			return true
+		}
+	}
+	return false
+}
+
+// Equivalent reports whether every comparison found the same behaviour.
+func Equivalent(results []Comparison) bool {
+	for _, c := range results {
+		if !c.Equal {
+			return false
+		}
+	}
+	return true
+}
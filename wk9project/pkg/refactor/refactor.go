@@ -0,0 +1,227 @@
+// Package refactor sends an existing Go module to the model with a
+// refactoring goal, applies the files it answers with and checks that the
+// new program behaves like the old one on recorded stdin sessions.
+package refactor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/gofix"
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+)
+
+// Files maps slash separated paths relative to the module root to file
+// contents.
+type Files map[string]string
+
+// Read returns the .go files of the module in dir, skipping hidden,
+// testdata and vendor directories.
+func Read(dir string) (Files, error) {
+	files := Files{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "testdata" || d.Name() == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .go files in %v", dir)
+	}
+	return files, nil
+}
+
+// Names returns the file paths in order.
+func (f Files) Names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Prompt asks for the module refactored towards goal with its behaviour
+// unchanged. module is the module path from go.mod, needed for imports
+// between packages.
+func Prompt(module string, files Files, goal string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Here is a go module named %v. Refactor it to reach this goal: %v\n\n", module, goal)
+	b.WriteString("The program must behave exactly as before: the same arguments and input must print the same output and exit with the same status. ")
+	fmt.Fprintf(&b, "If it seeds random numbers from the %v environment variable, keep doing so. ", project.SeedEnv)
+	fmt.Fprintf(&b, "New packages go in subdirectories and are imported as %v/<dir>. ", module)
+	b.WriteString("Respond with every .go file of the refactored module, including unchanged ones, and nothing else. ")
+	b.WriteString("Put each file in its own ```go block preceded by a line like \"// file: path/to/file.go\". Files you leave out are deleted.\n")
+	for _, name := range files.Names() {
+		fmt.Fprintf(&b, "\n// file: %v\n```go\n%v\n```\n", name, strings.TrimRight(files[name], "\n"))
+	}
+	return b.String()
+}
+
+// Parse reads the files out of a response written as Prompt asks. The
+// path may also be given as the first line inside the code block.
+func Parse(response string) (Files, error) {
+	files := Files{}
+	var pending string
+	var block []string
+	inBlock := false
+	for _, line := range strings.Split(response, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```") && !inBlock:
+			inBlock = true
+			block = block[:0]
+		case trimmed == "```" && inBlock:
+			inBlock = false
+			name := pending
+			if len(block) > 0 {
+				if first := fileMarker(block[0]); first != "" {
+					name, block = first, block[1:]
+				}
+			}
+			if name == "" {
+				return nil, fmt.Errorf("code block without a file name")
+			}
+			if err := files.add(name, strings.Join(block, "\n")+"\n"); err != nil {
+				return nil, err
+			}
+			pending = ""
+		case inBlock:
+			block = append(block, line)
+		default:
+			if name := fileMarker(trimmed); name != "" {
+				pending = name
+			}
+		}
+	}
+	if inBlock {
+		return nil, fmt.Errorf("unterminated code block")
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files in the response")
+	}
+	return files, nil
+}
+
+// fileMarker returns the path of lines like "// file: hand/hand.go",
+// "### hand/hand.go" or "**main.go**", or "" for other lines.
+func fileMarker(line string) string {
+	s := strings.TrimSpace(line)
+	s = strings.TrimLeft(s, "/#*` ")
+	s = strings.TrimPrefix(s, "file:")
+	s = strings.TrimPrefix(s, "File:")
+	s = strings.Trim(s, "*`: ")
+	if strings.ContainsAny(s, " \t") || !strings.HasSuffix(s, ".go") {
+		return ""
+	}
+	return s
+}
+
+func (f Files) add(name, code string) error {
+	clean := path.Clean(filepath.ToSlash(name))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("file %v is outside the module", name)
+	}
+	if _, ok := f[clean]; ok {
+		return fmt.Errorf("file %v appears twice", clean)
+	}
+	f[clean] = code
+	return nil
+}
+
+// Apply replaces the .go files of the module in dir with files: old files
+// are deleted and new ones written with their imports fixed and gofmt'd.
+func Apply(dir string, old, files Files) error {
+	for name := range old {
+		if _, ok := files[name]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+	}
+	for name, code := range files {
+		src, err := gofix.Normalize([]byte(code))
+		if err != nil {
+			src = []byte(code)
+		}
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, src, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore puts the module back to old after Apply.
+func Restore(dir string, old, applied Files) error {
+	for name := range applied {
+		if _, ok := old[name]; ok {
+			continue
+		}
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		// drop directories the refactoring created, if now empty
+		for d := filepath.Dir(p); d != filepath.Clean(dir); d = filepath.Dir(d) {
+			if os.Remove(d) != nil {
+				break
+			}
+		}
+	}
+	for name, code := range old {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(code), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ModulePath returns the module path declared in dir's go.mod.
+func ModulePath(dir string) (string, error) {
+	cmd := exec.Command("go", "mod", "edit", "-json")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("reading go.mod: %w", err)
+	}
+	var mod struct {
+		Module struct{ Path string }
+	}
+	if err := json.Unmarshal(out, &mod); err != nil {
+		return "", fmt.Errorf("reading go.mod: %w", err)
+	}
+	return mod.Module.Path, nil
+}
@@ -0,0 +1,90 @@
+package refactor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     Files
+	}{
+		{
+			name:     "comment marker",
+			response: "Here you go:\n// file: main.go\n```go\npackage main\n```\n",
+			want:     Files{"main.go": "package main\n"},
+		},
+		{
+			name:     "heading and bold markers",
+			response: "### hand/hand.go\n```go\npackage hand\n```\n**main.go**\n```\npackage main\n```",
+			want:     Files{"hand/hand.go": "package hand\n", "main.go": "package main\n"},
+		},
+		{
+			name:     "marker inside the block",
+			response: "```go\n// file: deck/deck.go\npackage deck\n\nfunc New() {}\n```",
+			want:     Files{"deck/deck.go": "package deck\n\nfunc New() {}\n"},
+		},
+		{
+			name:     "path is cleaned",
+			response: "File: `./cmd/../main.go`\n```go\npackage main\n```",
+			want:     Files{"main.go": "package main\n"},
+		},
+		{
+			name:     "prose with spaces is not a marker",
+			response: "// file: main.go\nThe main file, main.go\n```go\npackage main\n```",
+			want:     Files{"main.go": "package main\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.response)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"no files", "I could not refactor this.", "no files in the response"},
+		{"no file name", "```go\npackage main\n```", "code block without a file name"},
+		{"unterminated", "// file: main.go\n```go\npackage main\n", "unterminated code block"},
+		{"twice", "// file: main.go\n```go\n```\n// file: ./main.go\n```go\n```", "file main.go appears twice"},
+		{"outside", "// file: ../other/main.go\n```go\n```", "file ../other/main.go is outside the module"},
+		{"absolute", "// file: /tmp/main.go\n```go\n```", "file /tmp/main.go is outside the module"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.response); err == nil || err.Error() != tt.want {
+				t.Errorf("Parse() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptRoundTrip(t *testing.T) {
+	files := Files{
+		"main.go":      "package main\n\nfunc main() {}\n",
+		"hand/hand.go": "package hand\n",
+	}
+	prompt := Prompt("blackjack", files, "split the game logic out of main")
+	// the prompt's files are laid out the way the model is asked to answer
+	got, err := Parse(prompt[strings.Index(prompt, "\n// file:"):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, files) {
+		t.Errorf("Parse(Prompt()) = %q, want %q", got, files)
+	}
+}
@@ -0,0 +1,234 @@
+// Package retrieve indexes existing Go packages so generated programs can
+// reuse them. The exported API of each package is read with go/doc, the
+// declarations most relevant to a prompt are picked within a token budget
+// and rendered as context telling the model to import them.
+package retrieve
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Module is a module that indexed packages belong to. Generated projects
+// point a replace directive at Dir.
+type Module struct {
+	Path string
+	Dir  string
+}
+
+// Package is an importable package and its exported API.
+type Package struct {
+	ImportPath string
+	Name       string
+	Synopsis   string
+	Module     Module
+	Symbols    []Symbol
+}
+
+// Symbol is one exported declaration. Types carry their constructors and
+// method signatures in Decl.
+type Symbol struct {
+	Package *Package
+	Name    string
+	Doc     string
+	Decl    string
+}
+
+// Index is the exported API of a set of packages.
+type Index struct {
+	Packages []*Package
+	// Skipped lists directories that were not indexed and why.
+	Skipped []string
+}
+
+// Load indexes the packages in and below each path. Every path must be
+// inside a module. Main packages cannot be imported and are skipped.
+func Load(paths []string) (*Index, error) {
+	ix := &Index{}
+	seen := map[string]bool{}
+	for _, path := range paths {
+		root, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		mod, err := findModule(root)
+		if err != nil {
+			return nil, err
+		}
+		err = filepath.WalkDir(root, func(dir string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			if dir != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "testdata" || d.Name() == "vendor") {
+				return filepath.SkipDir
+			}
+			if dir != mod.Dir && fileExists(filepath.Join(dir, "go.mod")) {
+				// a nested module needs its own -context path
+				return filepath.SkipDir
+			}
+			if seen[dir] {
+				return nil
+			}
+			seen[dir] = true
+			return ix.loadPackage(mod, dir)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(ix.Packages) == 0 {
+		return nil, fmt.Errorf("no importable packages found in %v", strings.Join(paths, ", "))
+	}
+	return ix, nil
+}
+
+func (ix *Index) loadPackage(mod Module, dir string) error {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("parsing %v: %w", dir, err)
+	}
+
+	rel, err := filepath.Rel(mod.Dir, dir)
+	if err != nil {
+		return err
+	}
+	importPath := mod.Path
+	if rel != "." {
+		importPath += "/" + filepath.ToSlash(rel)
+	}
+
+	for name, astPkg := range pkgs {
+		if name == "main" {
+			ix.Skipped = append(ix.Skipped, dir+": package main cannot be imported")
+			continue
+		}
+		var files []*ast.File
+		for _, file := range astPkg.Files {
+			files = append(files, file)
+		}
+		docPkg, err := doc.NewFromFiles(fset, files, importPath)
+		if err != nil {
+			return fmt.Errorf("reading docs of %v: %w", dir, err)
+		}
+
+		pkg := &Package{
+			ImportPath: importPath,
+			Name:       docPkg.Name,
+			Synopsis:   docPkg.Synopsis(docPkg.Doc),
+			Module:     mod,
+		}
+		for _, v := range append(docPkg.Consts, docPkg.Vars...) {
+			pkg.add(docPkg, strings.Join(v.Names, " "), v.Doc, node(fset, v.Decl))
+		}
+		for _, f := range docPkg.Funcs {
+			pkg.add(docPkg, f.Name, f.Doc, signature(fset, f.Decl))
+		}
+		for _, t := range docPkg.Types {
+			decls := []string{node(fset, t.Decl)}
+			for _, f := range t.Funcs {
+				decls = append(decls, signature(fset, f.Decl))
+			}
+			for _, m := range t.Methods {
+				decls = append(decls, signature(fset, m.Decl))
+			}
+			pkg.add(docPkg, t.Name, t.Doc, strings.Join(decls, "\n"))
+		}
+		if len(pkg.Symbols) > 0 {
+			ix.Packages = append(ix.Packages, pkg)
+		}
+	}
+	return nil
+}
+
+func (p *Package) add(docPkg *doc.Package, name, text, decl string) {
+	p.Symbols = append(p.Symbols, Symbol{
+		Package: p,
+		Name:    name,
+		Doc:     docPkg.Synopsis(text),
+		Decl:    decl,
+	})
+}
+
+// signature prints a function declaration without its body.
+func signature(fset *token.FileSet, decl *ast.FuncDecl) string {
+	d := *decl
+	d.Body = nil
+	d.Doc = nil
+	return node(fset, &d)
+}
+
+func node(fset *token.FileSet, n ast.Node) string {
+	if gen, ok := n.(*ast.GenDecl); ok && gen.Doc != nil {
+		g := *gen
+		g.Doc = nil
+		n = &g
+	}
+	var b bytes.Buffer
+	if err := format.Node(&b, fset, n); err != nil {
+		return ""
+	}
+	return b.String()
+}
+
+// findModule returns the module containing dir.
+func findModule(dir string) (Module, error) {
+	for d := dir; ; d = filepath.Dir(d) {
+		path := filepath.Join(d, "go.mod")
+		if fileExists(path) {
+			modPath, err := modulePath(path)
+			if err != nil {
+				return Module{}, err
+			}
+			return Module{Path: modPath, Dir: d}, nil
+		}
+		if filepath.Dir(d) == d {
+			return Module{}, fmt.Errorf("%v is not inside a Go module", dir)
+		}
+	}
+}
+
+// modulePath reads the module directive of a go.mod file.
+func modulePath(gomod string) (string, error) {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			if unquoted, err := strconv.Unquote(fields[1]); err == nil {
+				return unquoted, nil
+			}
+			return fields[1], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%v has no module directive", gomod)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
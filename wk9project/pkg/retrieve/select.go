@@ -0,0 +1,187 @@
+package retrieve
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Tokens estimates the tokens in s at four characters per token, like
+// llm.Meter does.
+func Tokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// stopWords are too common in prompts to say anything about relevance.
+var stopWords = map[string]bool{
+	"and": true, "the": true, "for": true, "that": true, "with": true,
+	"program": true, "need": true, "want": true, "should": true, "each": true,
+	"this": true, "from": true, "into": true, "then": true, "have": true,
+	"print": true, "prints": true, "user": true, "use": true, "using": true,
+	"are": true, "all": true, "can": true, "when": true, "will": true,
+}
+
+// Select picks the symbols most relevant to query whose declarations fit
+// in budget tokens. A symbol is relevant when its name or doc shares words
+// with the query; matching the package as well ranks it higher. Types the
+// picked declarations refer to are added when they fit. The result keeps
+// index order.
+func (ix *Index) Select(query string, budget int) []Symbol {
+	want := terms(query)
+
+	type scored struct {
+		Symbol
+		score int
+		order int
+	}
+	var candidates []scored
+	for _, pkg := range ix.Packages {
+		pkgTerms := terms(pkg.Name + " " + pkg.Synopsis)
+		for _, sym := range pkg.Symbols {
+			score := 0
+			have := terms(sym.Name + " " + sym.Doc)
+			for term := range want {
+				if have[term] {
+					score += 2
+				}
+			}
+			if score > 0 {
+				for term := range want {
+					if pkgTerms[term] {
+						score++
+					}
+				}
+			}
+			candidates = append(candidates, scored{sym, score, len(candidates)})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	var picked []scored
+	isPicked := map[int]bool{}
+	used := 0
+	pick := func(c scored) {
+		cost := Tokens(c.Doc) + Tokens(c.Decl)
+		if isPicked[c.order] || used+cost > budget {
+			return
+		}
+		used += cost
+		isPicked[c.order] = true
+		picked = append(picked, c)
+	}
+	for _, c := range candidates {
+		if c.score > 0 {
+			pick(c)
+		}
+	}
+	for i := 0; i < len(picked); i++ {
+		refs := identifiers(picked[i].Decl)
+		for _, c := range candidates {
+			if c.Package == picked[i].Package && refs[c.Name] {
+				pick(c)
+			}
+		}
+	}
+	sort.Slice(picked, func(i, j int) bool {
+		return picked[i].order < picked[j].order
+	})
+
+	symbols := make([]Symbol, len(picked))
+	for i, c := range picked {
+		symbols[i] = c.Symbol
+	}
+	return symbols
+}
+
+// identifiers returns the words of a declaration as written.
+func identifiers(decl string) map[string]bool {
+	set := map[string]bool{}
+	for _, f := range strings.FieldsFunc(decl, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		set[f] = true
+	}
+	return set
+}
+
+// terms splits text into lowercase words, breaking camel case names apart
+// and dropping short and common words. Plural s is dropped so Card matches
+// cards.
+func terms(text string) map[string]bool {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	var prev rune
+	for _, r := range text {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+		prev = r
+	}
+	flush()
+
+	set := map[string]bool{}
+	for _, w := range words {
+		if len(w) < 3 || stopWords[w] {
+			continue
+		}
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = w[:len(w)-1]
+		}
+		set[w] = true
+	}
+	return set
+}
+
+// Modules returns the modules the symbols come from.
+func Modules(symbols []Symbol) []Module {
+	var mods []Module
+	seen := map[string]bool{}
+	for _, sym := range symbols {
+		if mod := sym.Package.Module; !seen[mod.Path] {
+			seen[mod.Path] = true
+			mods = append(mods, mod)
+		}
+	}
+	return mods
+}
+
+// Prompt renders the symbols grouped by package with instructions to
+// import and reuse them. It is empty when there are no symbols.
+func Prompt(symbols []Symbol) string {
+	if len(symbols) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Reuse these existing packages instead of writing your own versions of their types and functions. Import them with exactly the import paths shown and do not redefine anything declared here.\n")
+	var current *Package
+	for _, sym := range symbols {
+		if sym.Package != current {
+			current = sym.Package
+			fmt.Fprintf(&b, "\npackage %v // import %q\n", current.Name, current.ImportPath)
+			if current.Synopsis != "" {
+				fmt.Fprintf(&b, "// %v\n", current.Synopsis)
+			}
+		}
+		b.WriteString("\n")
+		if sym.Doc != "" {
+			fmt.Fprintf(&b, "// %v\n", sym.Doc)
+		}
+		b.WriteString(sym.Decl + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,375 @@
+package review
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+var checks = []func(*pass){
+	checkDeprecated,
+	checkIgnoredErrors,
+	checkPlaceholders,
+	checkGoroutineWrites,
+	checkGlobalState,
+}
+
+// deprecated lists standard library functions chatgpt still reaches for.
+// An empty name matches every function in the package.
+var deprecated = map[string]map[string]string{
+	"math/rand": {
+		"Seed": "rand.Seed is deprecated since Go 1.20, the global source is seeded automatically",
+		"Read": "rand.Read is deprecated since Go 1.20, use crypto/rand.Read",
+	},
+	"io/ioutil": {
+		"": "io/ioutil is deprecated since Go 1.16, use the io and os equivalents",
+	},
+	"strings": {
+		"Title": "strings.Title is deprecated since Go 1.18, use golang.org/x/text/cases",
+	},
+}
+
+func checkDeprecated(p *pass) {
+	for _, file := range p.files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			ident, ok := sel.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			pkgName, ok := p.info.Uses[ident].(*types.PkgName)
+			if !ok {
+				return true
+			}
+			funcs := deprecated[pkgName.Imported().Path()]
+			if msg, ok := funcs[sel.Sel.Name]; ok {
+				p.report(sel.Pos(), "deprecated", "%v", msg)
+			} else if msg, ok := funcs[""]; ok {
+				p.report(sel.Pos(), "deprecated", "%v.%v: %v", ident.Name, sel.Sel.Name, msg)
+			}
+			return true
+		})
+	}
+}
+
+// checkIgnoredErrors reports errors assigned to the blank identifier, like
+// input, _ := reader.ReadString('\n').
+func checkIgnoredErrors(p *pass) {
+	errType := types.Universe.Lookup("error").Type()
+	for _, file := range p.files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			assign, ok := n.(*ast.AssignStmt)
+			if !ok || len(assign.Rhs) != 1 {
+				return true
+			}
+			call, ok := assign.Rhs[0].(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			var results []types.Type
+			switch t := p.info.Types[call].Type.(type) {
+			case *types.Tuple:
+				for i := 0; i < t.Len(); i++ {
+					results = append(results, t.At(i).Type())
+				}
+			case nil:
+				return true
+			default:
+				results = []types.Type{t}
+			}
+			if len(results) != len(assign.Lhs) {
+				return true
+			}
+			for i, lhs := range assign.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && ident.Name == "_" && types.Identical(results[i], errType) {
+					p.report(ident.Pos(), "ignored-error", "error from %v is ignored", callName(call))
+				}
+			}
+			return true
+		})
+	}
+}
+
+// checkPlaceholders reports functions that only return a constant while
+// ignoring their parameters or carrying an "implement me" comment, like
+// isWikipediaArticle returning true.
+func checkPlaceholders(p *pass) {
+	for _, file := range p.files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || len(fn.Body.List) != 1 {
+				continue
+			}
+			ret, ok := fn.Body.List[0].(*ast.ReturnStmt)
+			if !ok || len(ret.Results) == 0 || !allConstant(p, ret.Results) {
+				continue
+			}
+			if hasTodo(file, fn.Body) || (fn.Type.Params.NumFields() > 0 && !usesParams(p, fn)) {
+				p.report(fn.Name.Pos(), "placeholder", "%v always returns %v and looks unimplemented", fn.Name.Name, exprString(ret.Results[0]))
+			}
+		}
+	}
+}
+
+// checkGoroutineWrites reports appends to shared slices and writes to
+// shared maps inside functions started with go that are not between a
+// Lock and Unlock call.
+func checkGoroutineWrites(p *pass) {
+	started := map[*ast.FuncDecl]bool{}
+	var literals []*ast.FuncLit
+	decls := map[types.Object]*ast.FuncDecl{}
+	for _, file := range p.files {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				decls[p.info.Defs[fn.Name]] = fn
+			}
+		}
+	}
+	for _, file := range p.files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			stmt, ok := n.(*ast.GoStmt)
+			if !ok {
+				return true
+			}
+			switch fun := stmt.Call.Fun.(type) {
+			case *ast.FuncLit:
+				literals = append(literals, fun)
+			case *ast.Ident:
+				if fn := decls[p.info.Uses[fun]]; fn != nil {
+					started[fn] = true
+				}
+			}
+			return true
+		})
+	}
+
+	for fn := range started {
+		fn := fn
+		p.walkLocked(fn.Body.List, false, func(target ast.Expr, locked bool) {
+			if !locked && p.shared(target, fn.Pos(), fn.Body) {
+				p.report(target.Pos(), "race", "%v is written from goroutine %v without holding a lock", exprString(target), fn.Name.Name)
+			}
+		})
+	}
+	for _, lit := range literals {
+		lit := lit
+		p.walkLocked(lit.Body.List, false, func(target ast.Expr, locked bool) {
+			if !locked && p.shared(target, lit.Pos(), lit.Body) {
+				p.report(target.Pos(), "race", "%v is written from a goroutine without holding a lock", exprString(target))
+			}
+		})
+	}
+}
+
+// walkLocked visits the shared write targets in stmts, tracking whether a
+// mutex is held. Lock sets the state, Unlock clears it and a deferred
+// Unlock keeps it until the end of the function.
+func (p *pass) walkLocked(stmts []ast.Stmt, locked bool, visit func(ast.Expr, bool)) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.ExprStmt:
+			switch {
+			case isCallTo(s.X, "Lock"), isCallTo(s.X, "RLock"):
+				locked = true
+			case isCallTo(s.X, "Unlock"), isCallTo(s.X, "RUnlock"):
+				locked = false
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range s.Lhs {
+				if idx, ok := lhs.(*ast.IndexExpr); ok {
+					visit(idx.X, locked)
+				} else if i < len(s.Rhs) && isCallTo(s.Rhs[i], "append") {
+					visit(lhs, locked)
+				}
+			}
+		}
+
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.BlockStmt:
+				p.walkLocked(n.List, locked, visit)
+				return false
+			case *ast.CaseClause:
+				p.walkLocked(n.Body, locked, visit)
+				return false
+			case *ast.CommClause:
+				p.walkLocked(n.Body, locked, visit)
+				return false
+			case *ast.FuncLit:
+				// callbacks run synchronously with the current lock state
+				p.walkLocked(n.Body.List, locked, visit)
+				return false
+			case *ast.GoStmt:
+				// nested goroutines are checked on their own
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// shared reports whether target refers to memory other goroutines can
+// reach: package level variables, values behind pointer parameters and
+// variables captured from outside the function.
+func (p *pass) shared(target ast.Expr, start token.Pos, body *ast.BlockStmt) bool {
+	deref := false
+	for {
+		switch e := target.(type) {
+		case *ast.StarExpr:
+			deref = true
+			target = e.X
+			continue
+		case *ast.ParenExpr:
+			target = e.X
+			continue
+		case *ast.SelectorExpr:
+			target = e.X
+			continue
+		case *ast.IndexExpr:
+			target = e.X
+			continue
+		}
+		break
+	}
+	ident, ok := target.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	obj, ok := p.info.Uses[ident].(*types.Var)
+	if !ok {
+		return false
+	}
+	switch {
+	case obj.Pos() >= body.Pos() && obj.Pos() <= body.End():
+		return false
+	case obj.Pos() >= start && obj.Pos() < body.Pos():
+		// parameters are only shared through pointers
+		return deref
+	}
+	return true
+}
+
+// checkGlobalState reports package level variables that functions modify.
+func checkGlobalState(p *pass) {
+	if p.pkg == nil {
+		return
+	}
+	reported := map[types.Object]bool{}
+	for _, file := range p.files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				var targets []ast.Expr
+				switch s := n.(type) {
+				case *ast.AssignStmt:
+					if s.Tok != token.DEFINE {
+						targets = s.Lhs
+					}
+				case *ast.IncDecStmt:
+					targets = []ast.Expr{s.X}
+				}
+				for _, target := range targets {
+					ident := rootIdent(target)
+					if ident == nil {
+						continue
+					}
+					obj := p.info.Uses[ident]
+					if obj == nil || obj.Parent() != p.pkg.Scope() || reported[obj] {
+						continue
+					}
+					reported[obj] = true
+					p.report(ident.Pos(), "global-state", "package level variable %v is modified in %v; keep it in a struct or pass it explicitly", ident.Name, fn.Name.Name)
+				}
+				return true
+			})
+		}
+	}
+}
+
+func rootIdent(e ast.Expr) *ast.Ident {
+	for {
+		switch x := e.(type) {
+		case *ast.Ident:
+			return x
+		case *ast.IndexExpr:
+			e = x.X
+		case *ast.SelectorExpr:
+			e = x.X
+		case *ast.StarExpr:
+			e = x.X
+		case *ast.ParenExpr:
+			e = x.X
+		default:
+			return nil
+		}
+	}
+}
+
+// callName returns the called expression of a call, like reader.ReadString,
+// or "" if e is not a call.
+func callName(e ast.Expr) string {
+	call, ok := e.(*ast.CallExpr)
+	if !ok {
+		return ""
+	}
+	return exprString(call.Fun)
+}
+
+// isCallTo reports whether e calls a function or method with the given name.
+func isCallTo(e ast.Expr, name string) bool {
+	fun := callName(e)
+	return fun == name || strings.HasSuffix(fun, "."+name)
+}
+
+func allConstant(p *pass, exprs []ast.Expr) bool {
+	for _, e := range exprs {
+		if ident, ok := e.(*ast.Ident); ok && ident.Name == "nil" {
+			continue
+		}
+		if tv, ok := p.info.Types[e]; !ok || tv.Value == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func usesParams(p *pass, fn *ast.FuncDecl) bool {
+	params := map[types.Object]bool{}
+	for _, field := range fn.Type.Params.List {
+		for _, name := range field.Names {
+			params[p.info.Defs[name]] = true
+		}
+	}
+	used := false
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && params[p.info.Uses[ident]] {
+			used = true
+		}
+		return !used
+	})
+	return used
+}
+
+func hasTodo(file *ast.File, body *ast.BlockStmt) bool {
+	for _, group := range file.Comments {
+		if group.Pos() < body.Pos() || group.End() > body.End() {
+			continue
+		}
+		text := strings.ToLower(group.Text())
+		if strings.Contains(text, "todo") || strings.Contains(text, "implement") {
+			return true
+		}
+	}
+	return false
+}
+
+func exprString(e ast.Expr) string {
+	return types.ExprString(e)
+}
@@ -0,0 +1,126 @@
+// Package review statically checks generated programs for the problems
+// chatgpt keeps making: deprecated calls, ignored errors, placeholder
+// functions, unguarded writes from goroutines and global mutable state.
+package review
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Finding is a single problem found in the reviewed code.
+type Finding struct {
+	Pos     token.Position
+	Check   string
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%v: [%v] %v", f.Pos, f.Check, f.Message)
+}
+
+// pass holds everything a check needs about one package.
+type pass struct {
+	fset     *token.FileSet
+	files    []*ast.File
+	pkg      *types.Package
+	info     *types.Info
+	findings []Finding
+}
+
+func (p *pass) report(pos token.Pos, check, format string, args ...any) {
+	p.findings = append(p.findings, Finding{
+		Pos:     p.fset.Position(pos),
+		Check:   check,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Dir reviews every package under dir. Type errors in the code do not stop
+// the review; the checks run on whatever type information is available.
+func Dir(dir string) ([]Finding, error) {
+	var findings []Finding
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "testdata" || d.Name() == "vendor") {
+			return filepath.SkipDir
+		}
+		found, err := reviewPackage(path)
+		if err != nil {
+			return err
+		}
+		findings = append(findings, found...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i].Pos, findings[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Line < b.Line
+	})
+	return findings, nil
+}
+
+func reviewPackage(dir string) ([]Finding, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %v: %w", dir, err)
+	}
+
+	var findings []Finding
+	for _, astPkg := range pkgs {
+		p := &pass{fset: fset}
+		for _, file := range astPkg.Files {
+			p.files = append(p.files, file)
+		}
+
+		p.info = &types.Info{
+			Types: map[ast.Expr]types.TypeAndValue{},
+			Defs:  map[*ast.Ident]types.Object{},
+			Uses:  map[*ast.Ident]types.Object{},
+		}
+		conf := types.Config{
+			Importer: importer.ForCompiler(fset, "source", nil),
+			Error:    func(error) {}, // generated code often does not compile
+		}
+		p.pkg, _ = conf.Check(astPkg.Name, fset, p.files, p.info)
+
+		for _, check := range checks {
+			check(p)
+		}
+		findings = append(findings, p.findings...)
+	}
+	return findings, nil
+}
+
+// Report formats findings one per line with a summary.
+func Report(findings []Finding) string {
+	var b strings.Builder
+	for _, f := range findings {
+		b.WriteString(f.String())
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%d problem(s) found\n", len(findings))
+	return b.String()
+}
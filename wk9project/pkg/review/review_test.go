@@ -0,0 +1,158 @@
+package review
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		checks []string
+	}{
+		{
+			name: "clean",
+			src: `package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+func main() {
+	var mu sync.Mutex
+	var results []int
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, n)
+		}(i)
+	}
+	wg.Wait()
+	if _, err := fmt.Println(results); err != nil {
+		panic(err)
+	}
+}
+`,
+		},
+		{
+			name: "deprecated",
+			src: `package main
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"time"
+)
+
+func main() {
+	rand.Seed(time.Now().UnixNano())
+	data, err := ioutil.ReadFile("x")
+	_, _ = data, err
+}
+`,
+			checks: []string{"deprecated", "deprecated"},
+		},
+		{
+			name: "ignored error",
+			src: `package main
+
+import (
+	"bufio"
+	"os"
+)
+
+func main() {
+	reader := bufio.NewReader(os.Stdin)
+	line, _ := reader.ReadString('\n')
+	_ = line
+}
+`,
+			checks: []string{"ignored-error"},
+		},
+		{
+			name: "placeholder",
+			src: `package main
+
+func isArticle(url string) bool {
+	return true
+}
+
+func answer() int {
+	// TODO: implement
+	return 42
+}
+
+func double(n int) int {
+	return n * 2
+}
+
+func main() {
+	_, _, _ = isArticle(""), answer(), double(1)
+}
+`,
+			checks: []string{"placeholder", "placeholder"},
+		},
+		{
+			name: "goroutine write",
+			src: `package main
+
+func main() {
+	seen := map[string]bool{}
+	done := make(chan bool)
+	go func() {
+		seen["a"] = true
+		local := map[string]bool{}
+		local["b"] = true
+		done <- true
+	}()
+	<-done
+}
+`,
+			checks: []string{"race"},
+		},
+		{
+			name: "global state",
+			src: `package main
+
+var count int
+
+func inc() {
+	count++
+}
+
+func main() {
+	inc()
+	count = 0
+}
+`,
+			checks: []string{"global-state"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			findings, err := Dir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var checks []string
+			for _, f := range findings {
+				checks = append(checks, f.Check)
+			}
+			if !reflect.DeepEqual(checks, tt.checks) {
+				t.Errorf("checks = %q, want %q\n%v", checks, tt.checks, Report(findings))
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import "sync"
+
+// logBuffer collects a project's log output and wakes up readers that are
+// streaming it whenever something is written.
+type logBuffer struct {
+	mu      sync.Mutex
+	data    []byte
+	active  bool
+	changed chan struct{}
+}
+
+func newLogBuffer() *logBuffer {
+	return &logBuffer{changed: make(chan struct{})}
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	b.notify()
+	return len(p), nil
+}
+
+// setActive marks whether a job is writing to the log. Streams end once
+// they have caught up with an inactive log.
+func (b *logBuffer) setActive(active bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.active = active
+	b.notify()
+}
+
+// since returns the log after offset, a channel closed on the next change
+// and whether a job is still writing.
+func (b *logBuffer) since(offset int) ([]byte, <-chan struct{}, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if offset > len(b.data) {
+		offset = len(b.data)
+	}
+	return b.data[offset:len(b.data):len(b.data)], b.changed, b.active
+}
+
+func (b *logBuffer) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
@@ -0,0 +1,351 @@
+// Package server exposes makego over a local HTTP/JSON API. Generation and
+// edit requests are queued and run by a fixed number of workers.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+)
+
+// Status values of a project.
+const (
+	Queued  = "queued"
+	Running = "running"
+	Done    = "done"
+	Failed  = "failed"
+)
+
+var nameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// Project is a generated program tracked by the server.
+type Project struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Prompt  string    `json:"prompt"`
+	Dir     string    `json:"dir"`
+	Status  string    `json:"status"`
+	Error   string    `json:"error,omitempty"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+
+	log *logBuffer
+	// run serializes jobs on the same project
+	run sync.Mutex
+}
+
+type job struct {
+	project     *Project
+	instruction string // empty for the initial generation
+	race        bool
+}
+
+// Server handles the makego API.
+type Server struct {
+	// Template and Repairs are used for every generation, see
+	// project.Options. Env is the environment generated programs' tests
+	// run with, nil inherits the server's. Set them before serving.
+	Template string
+	Repairs  int
+	Env      []string
+
+	client project.Asker
+	root   string
+	jobs   chan job
+
+	mu       sync.Mutex
+	projects map[string]*Project
+	nextID   int
+}
+
+// New creates a server that generates projects under root and starts
+// workers goroutines to run queued jobs. At most queue jobs wait at once.
+func New(client project.Asker, root string, workers, queue int) *Server {
+	s := &Server{
+		client:   client,
+		root:     root,
+		jobs:     make(chan job, queue),
+		projects: map[string]*Project{},
+	}
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+	return s
+}
+
+func (s *Server) work() {
+	for j := range s.jobs {
+		s.runJob(j)
+	}
+}
+
+func (s *Server) runJob(j job) {
+	p := j.project
+	p.run.Lock()
+	defer p.run.Unlock()
+
+	s.setStatus(p, Running, nil)
+	p.log.setActive(true)
+	defer p.log.setActive(false)
+
+	gen := &project.Generator{Client: s.client, Log: p.log, Env: s.Env}
+	var err error
+	if j.instruction == "" {
+		_, err = gen.Create(project.Options{
+			Name:     p.Name,
+			Prompt:   p.Prompt,
+			Root:     s.root,
+			Race:     j.race,
+			Template: s.Template,
+			Repairs:  s.Repairs,
+		})
+	} else {
+		err = gen.Edit(p.Dir, j.instruction)
+	}
+	if err != nil {
+		fmt.Fprintf(p.log, "Error: %v\n", err)
+		s.setStatus(p, Failed, err)
+		return
+	}
+	s.setStatus(p, Done, nil)
+}
+
+func (s *Server) setStatus(p *Project, status string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.Status = status
+	p.Error = ""
+	if err != nil {
+		p.Error = err.Error()
+	}
+	p.Updated = time.Now()
+}
+
+// enqueue queues a job without blocking, failing when the queue is full.
+func (s *Server) enqueue(j job) error {
+	select {
+	case s.jobs <- j:
+		return nil
+	default:
+		return errors.New("job queue is full, try again later")
+	}
+}
+
+// ServeHTTP routes:
+//
+//	POST /projects              {"name": "...", "prompt": "...", "race": false}
+//	GET  /projects/{id}
+//	GET  /projects/{id}/logs    streams the log until the current job ends
+//	POST /projects/{id}/edit    {"instruction": "..."}
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	if parts[0] != "projects" || len(parts) > 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		s.create(w, r)
+		return
+	}
+
+	p := s.project(parts[1])
+	if p == nil {
+		writeError(w, http.StatusNotFound, errors.New("project not found"))
+		return
+	}
+
+	action := ""
+	if len(parts) == 3 {
+		action = parts[2]
+	}
+	switch action {
+	case "":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		writeJSON(w, http.StatusOK, p)
+	case "logs":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.logs(w, r, p)
+	case "edit":
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		s.edit(w, r, p)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) project(id string) *Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.projects[id]
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name   string `json:"name"`
+		Prompt string `json:"prompt"`
+		Race   bool   `json:"race"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("decoding request: %w", err))
+		return
+	}
+	if !nameRe.MatchString(req.Name) {
+		writeError(w, http.StatusBadRequest, errors.New("name must start with a letter and contain only letters, digits, - and _"))
+		return
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("prompt is required"))
+		return
+	}
+
+	opts := project.Options{Name: req.Name, Root: s.root}
+	s.mu.Lock()
+	for _, other := range s.projects {
+		if other.Name == req.Name {
+			s.mu.Unlock()
+			writeError(w, http.StatusConflict, errors.New("a project with that name already exists"))
+			return
+		}
+	}
+	if _, err := os.Stat(opts.Dir()); err == nil {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, errors.New("project directory already exists"))
+		return
+	}
+	s.nextID++
+	now := time.Now()
+	p := &Project{
+		ID:      strconv.Itoa(s.nextID),
+		Name:    req.Name,
+		Prompt:  req.Prompt,
+		Dir:     opts.Dir(),
+		Status:  Queued,
+		Created: now,
+		Updated: now,
+		log:     newLogBuffer(),
+	}
+	s.projects[p.ID] = p
+	s.mu.Unlock()
+
+	if err := s.enqueue(job{project: p, race: req.Race}); err != nil {
+		s.mu.Lock()
+		delete(s.projects, p.ID)
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusAccepted, p)
+}
+
+func (s *Server) edit(w http.ResponseWriter, r *http.Request, p *Project) {
+	var req struct {
+		Instruction string `json:"instruction"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("decoding request: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.Instruction) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("instruction is required"))
+		return
+	}
+
+	// mark it queued first so a worker picking the job up right away wins
+	s.mu.Lock()
+	status, prevErr, updated := p.Status, p.Error, p.Updated
+	s.mu.Unlock()
+	s.setStatus(p, Queued, nil)
+	if err := s.enqueue(job{project: p, instruction: req.Instruction}); err != nil {
+		s.mu.Lock()
+		p.Status, p.Error, p.Updated = status, prevErr, updated
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusAccepted, p)
+}
+
+// logs writes the project log and keeps streaming new output until the
+// running job finishes or the client goes away.
+func (s *Server) logs(w http.ResponseWriter, r *http.Request, p *Project) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	flusher, _ := w.(http.Flusher)
+
+	offset := 0
+	for {
+		data, changed, active := p.log.since(offset)
+		if len(data) > 0 {
+			if _, err := w.Write(data); err != nil {
+				return
+			}
+			offset += len(data)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if !active && s.finished(p) {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// finished reports whether no job is queued or running for p.
+func (s *Server) finished(p *Project) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return p.Status == Done || p.Status == Failed
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
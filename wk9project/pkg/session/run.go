@@ -0,0 +1,194 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of running a transcript.
+type Result struct {
+	Passed bool
+	// Failed is the step that did not pass.
+	Failed *Step
+	Reason string
+	// Output is everything the program printed.
+	Output string
+}
+
+func (r Result) String() string {
+	if r.Passed {
+		return "passed"
+	}
+	return fmt.Sprintf("failed at %v: %v", r.Failed, r.Reason)
+}
+
+// maxOutput bounds how much output is kept from a runaway program.
+const maxOutput = 1 << 20
+
+// output collects what the program prints and signals new data.
+type output struct {
+	mu      sync.Mutex
+	data    []byte
+	closed  bool
+	changed chan struct{}
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if room := maxOutput - len(o.data); room > 0 {
+		o.data = append(o.data, p[:min(len(p), room)]...)
+	}
+	close(o.changed)
+	o.changed = make(chan struct{})
+	return len(p), nil
+}
+
+func (o *output) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed = true
+	close(o.changed)
+	o.changed = make(chan struct{})
+}
+
+func (o *output) snapshot() (string, <-chan struct{}, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return string(o.data), o.changed, o.closed
+}
+
+// Run executes the transcript against the program at bin. The program is
+// killed when the transcript ends or a step fails.
+func Run(bin string, t *Transcript) (*Result, error) {
+	cmd := exec.Command(bin, t.Args...)
+	cmd.Env = t.Env
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = cmd.Stdout
+	out := &output{changed: make(chan struct{})}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting %v: %w", bin, err)
+	}
+
+	copied := make(chan struct{})
+	go func() {
+		io.Copy(out, pipe)
+		out.close()
+		close(copied)
+	}()
+
+	exited := make(chan error, 1)
+	var waitOnce sync.Once
+	wait := func() <-chan error {
+		waitOnce.Do(func() {
+			go func() {
+				<-copied
+				exited <- cmd.Wait()
+			}()
+		})
+		return exited
+	}
+	defer func() {
+		stdin.Close()
+		cmd.Process.Kill()
+		<-wait()
+	}()
+
+	result := &Result{Passed: true}
+	fail := func(step Step, format string, args ...any) {
+		result.Passed = false
+		result.Failed = &step
+		result.Reason = fmt.Sprintf(format, args...)
+	}
+
+	pos := 0
+	for _, step := range t.Steps {
+		switch step.Kind {
+		case Expect:
+			end, reason := expect(out, pos, step)
+			if reason != "" {
+				fail(step, "%v", reason)
+			}
+			pos = end
+		case Send:
+			if _, err := io.WriteString(stdin, step.Text+"\n"); err != nil {
+				fail(step, "writing to stdin: %v", err)
+			}
+		case EOF:
+			stdin.Close()
+		case Exit:
+			stdin.Close()
+			select {
+			case err := <-wait():
+				// keep the status for the deferred wait
+				exited <- err
+				code := 0
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					code = exitErr.ExitCode()
+				} else if err != nil {
+					fail(step, "%v", err)
+					break
+				}
+				if code != step.Code {
+					fail(step, "exit status %d", code)
+				}
+			case <-time.After(step.Timeout):
+				fail(step, "program still running after %v", step.Timeout)
+			}
+		}
+		if !result.Passed {
+			break
+		}
+	}
+
+	result.Output, _, _ = out.snapshot()
+	return result, nil
+}
+
+// expect waits until the output after pos matches the step and returns the
+// position after the match, or a reason it failed.
+func expect(out *output, pos int, step Step) (int, string) {
+	timer := time.NewTimer(step.Timeout)
+	defer timer.Stop()
+	for {
+		data, changed, closed := out.snapshot()
+		rest := data[pos:]
+		if step.Re != nil {
+			if loc := step.Re.FindStringIndex(rest); loc != nil {
+				return pos + loc[1], ""
+			}
+		} else if i := strings.Index(rest, step.Text); i >= 0 {
+			return pos + i + len(step.Text), ""
+		}
+		if closed {
+			return pos, fmt.Sprintf("program exited, output since last match: %q", tail(rest))
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return pos, fmt.Sprintf("timed out after %v, output since last match: %q", step.Timeout, tail(rest))
+		}
+	}
+}
+
+func tail(s string) string {
+	const max = 200
+	if len(s) > max {
+		return "..." + s[len(s)-max:]
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+// Package session drives interactive command line programs from a
+// transcript of expected prompts and inputs, expect style.
+//
+// A transcript has one directive per line:
+//
+//	# comments and blank lines are ignored
+//	args -level hard        program arguments, before any other step
+//	timeout 5s              per step timeout for the steps that follow
+//	expect Enter your bet   wait for text in the output
+//	expect /hand \(\d+\)/   wait for output matching a regular expression
+//	send 100                write a line to stdin
+//	eof                     close stdin
+//	exit 0                  wait for the program to exit with this status
+package session
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is the per step timeout when a transcript sets none.
+const DefaultTimeout = 5 * time.Second
+
+// Step kinds.
+const (
+	Expect = "expect"
+	Send   = "send"
+	EOF    = "eof"
+	Exit   = "exit"
+)
+
+// Step is one directive of a transcript.
+type Step struct {
+	Line    int
+	Kind    string
+	Text    string
+	Re      *regexp.Regexp
+	Code    int
+	Timeout time.Duration
+}
+
+func (s Step) String() string {
+	switch s.Kind {
+	case Expect:
+		if s.Re != nil {
+			return fmt.Sprintf("line %d: expect /%v/", s.Line, s.Re)
+		}
+		return fmt.Sprintf("line %d: expect %q", s.Line, s.Text)
+	case Send:
+		return fmt.Sprintf("line %d: send %q", s.Line, s.Text)
+	case Exit:
+		return fmt.Sprintf("line %d: exit %d", s.Line, s.Code)
+	}
+	return fmt.Sprintf("line %d: %v", s.Line, s.Kind)
+}
+
+// Transcript is a parsed session script.
+type Transcript struct {
+	Name  string
+	Args  []string
+	Steps []Step
+	// Env is the program's environment; nil inherits makego's.
+	Env []string
+}
+
+// Load reads a transcript file.
+func Load(path string) (*Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	t.Name = path
+	return t, nil
+}
+
+// Parse reads a transcript.
+func Parse(r io.Reader) (*Transcript, error) {
+	t := &Transcript{}
+	timeout := DefaultTimeout
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		directive, arg, _ := strings.Cut(line, " ")
+		step := Step{Line: n, Kind: directive, Timeout: timeout}
+		switch directive {
+		case "args":
+			if len(t.Steps) > 0 {
+				return nil, fmt.Errorf("line %d: args must come before the first step", n)
+			}
+			t.Args = strings.Fields(arg)
+			continue
+		case "timeout":
+			d, err := time.ParseDuration(strings.TrimSpace(arg))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			timeout = d
+			continue
+		case Expect:
+			if len(arg) > 1 && strings.HasPrefix(arg, "/") && strings.HasSuffix(arg, "/") {
+				re, err := regexp.Compile(arg[1 : len(arg)-1])
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", n, err)
+				}
+				step.Re = re
+			} else if arg == "" {
+				return nil, fmt.Errorf("line %d: expect needs text or a /regexp/", n)
+			}
+			step.Text = arg
+		case Send:
+			step.Text = arg
+		case EOF:
+		case Exit:
+			code, err := strconv.Atoi(strings.TrimSpace(arg))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: exit needs a status code", n)
+			}
+			step.Code = code
+		default:
+			return nil, fmt.Errorf("line %d: unknown directive %q", n, directive)
+		}
+		t.Steps = append(t.Steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,101 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		args    []string
+		steps   []string
+		timeout time.Duration
+	}{
+		{
+			name:    "steps",
+			in:      "expect Enter your bet\nsend 10\neof\nexit 0\n",
+			steps:   []string{`line 1: expect "Enter your bet"`, `line 2: send "10"`, "line 3: eof", "line 4: exit 0"},
+			timeout: DefaultTimeout,
+		},
+		{
+			name:    "args and timeout",
+			in:      "# a comment\n\nargs -level hard\ntimeout 2s\nexpect /hand \\(\\d+\\)/\r\n",
+			args:    []string{"-level", "hard"},
+			steps:   []string{`line 5: expect /hand \(\d+\)/`},
+			timeout: 2 * time.Second,
+		},
+		{
+			name:    "empty send",
+			in:      "send\nexit 3",
+			steps:   []string{`line 1: send ""`, "line 2: exit 3"},
+			timeout: DefaultTimeout,
+		},
+		{
+			name:    "a lone slash is text",
+			in:      "expect /",
+			steps:   []string{`line 1: expect "/"`},
+			timeout: DefaultTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := Parse(strings.NewReader(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(tr.Args, " ") != strings.Join(tt.args, " ") {
+				t.Errorf("Args = %q, want %q", tr.Args, tt.args)
+			}
+			if len(tr.Steps) != len(tt.steps) {
+				t.Fatalf("got %d steps %v, want %v", len(tr.Steps), tr.Steps, tt.steps)
+			}
+			for i, step := range tr.Steps {
+				if step.String() != tt.steps[i] {
+					t.Errorf("step %d = %v, want %v", i, step, tt.steps[i])
+				}
+				if step.Timeout != tt.timeout {
+					t.Errorf("step %d timeout = %v, want %v", i, step.Timeout, tt.timeout)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"unknown directive", "expect ok\nwait 5", `line 2: unknown directive "wait"`},
+		{"args after a step", "send 1\nargs -x", "line 2: args must come before the first step"},
+		{"bad timeout", "timeout soon", "line 1:"},
+		{"empty expect", "expect", "line 1: expect needs text or a /regexp/"},
+		{"bad regexp", "expect /(/", "line 1:"},
+		{"exit without status", "exit", "line 1: exit needs a status code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.in))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("Parse() = %v, want an error starting %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tr, err := Load("../../specs/sessions/blackjack-stand.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Name != "../../specs/sessions/blackjack-stand.txt" || len(tr.Steps) == 0 {
+		t.Errorf("Load() = %+v", tr)
+	}
+	if _, err := Load("missing.txt"); err == nil {
+		t.Error("Load() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,222 @@
+package spec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/project"
+	"github.com/jeremycruzz/msds301-wk9/pkg/session"
+)
+
+// exampleTimeout bounds each example session.
+const exampleTimeout = 10 * time.Second
+
+// Asker is the part of the chatgpt client used for self assessment.
+type Asker interface {
+	AskCustom(prompt string) (string, error)
+}
+
+// Result is the outcome of checking one requirement.
+type Result struct {
+	ID     string `json:"id"`
+	Text   string `json:"text"`
+	Met    bool   `json:"met"`
+	Method string `json:"method"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Check verifies every requirement of s against the project in dir. Tests
+// and examples are run for real; the rest are assessed by client, or
+// reported unmet when client is nil.
+func Check(dir string, s *Spec, client Asker) ([]Result, error) {
+	for _, test := range s.Tests {
+		data, err := os.ReadFile(filepath.Join(s.dir, test))
+		if err != nil {
+			return nil, fmt.Errorf("reading test file: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(test)), data, 0644); err != nil {
+			return nil, fmt.Errorf("copying test file: %w", err)
+		}
+	}
+
+	bin, err := project.BuildBinary(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(filepath.Dir(bin))
+
+	examples := map[string]Example{}
+	for _, e := range s.Examples {
+		examples[e.Name] = e
+	}
+
+	results := make([]Result, len(s.Requirements))
+	var assess []int
+	for i, r := range s.Requirements {
+		results[i] = Result{ID: r.ID, Text: r.Text, Method: r.Method()}
+		switch results[i].Method {
+		case "test":
+			results[i].Met, results[i].Detail = runTest(dir, r.Test, s.Env)
+		case "example":
+			results[i].Met, results[i].Detail = RunExample(bin, examples[r.Example], s.Env)
+		case "session":
+			results[i].Met, results[i].Detail = runSession(bin, filepath.Join(s.dir, r.Session), s.Env)
+		default:
+			assess = append(assess, i)
+		}
+	}
+
+	if len(assess) > 0 {
+		if client == nil {
+			for _, i := range assess {
+				results[i].Detail = "no test or example and no model to ask"
+			}
+		} else if err := selfAssess(dir, client, results, assess); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func runTest(dir, name string, env []string) (bool, string) {
+	cmd := exec.Command("go", "test", "-count=1", "-run", "^"+name+"$", ".")
+	cmd.Dir = dir
+	cmd.Env = env
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, lastLines(string(out), 5)
+	}
+	if bytes.Contains(out, []byte("no tests to run")) {
+		return false, "test " + name + " not found"
+	}
+	return true, ""
+}
+
+func runSession(bin, path string, env []string) (bool, string) {
+	t, err := session.Load(path)
+	if err != nil {
+		return false, err.Error()
+	}
+	t.Env = env
+	result, err := session.Run(bin, t)
+	if err != nil {
+		return false, err.Error()
+	}
+	if !result.Passed {
+		return false, result.String()
+	}
+	return true, ""
+}
+
+// RunExample runs the binary with the example's arguments and input and
+// checks that the expected output lines appear in order. A nil env
+// inherits makego's environment.
+func RunExample(bin string, e Example, env []string) (bool, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), exampleTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, bin, e.Args...)
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader(e.Input)
+	out, _ := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return false, fmt.Sprintf("timed out after %v", exampleTimeout)
+	}
+
+	rest := string(out)
+	for _, want := range e.Output {
+		i := strings.Index(rest, want)
+		if i < 0 {
+			return false, fmt.Sprintf("output missing %q", want)
+		}
+		rest = rest[i+len(want):]
+	}
+	return true, ""
+}
+
+// selfAssess asks chatgpt whether the code meets the requirements at the
+// given indexes of results.
+func selfAssess(dir string, client Asker, results []Result, indexes []int) error {
+	code, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		return fmt.Errorf("reading main.go: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("Here is a go program followed by a list of requirements. For each requirement decide whether the program fully implements it. ")
+	b.WriteString(`Only respond with a JSON array of objects like {"id": "R1", "met": true, "reason": "..."} and nothing else.`)
+	b.WriteString("\n\nRequirements:\n")
+	for _, i := range indexes {
+		fmt.Fprintf(&b, "- %v: %v\n", results[i].ID, results[i].Text)
+	}
+	b.WriteString("\nProgram:\n")
+	b.Write(code)
+
+	response, err := client.AskCustom(b.String())
+	if err != nil {
+		return fmt.Errorf("asking chatgpt: %w", err)
+	}
+	start, end := strings.Index(response, "["), strings.LastIndex(response, "]")
+	if start < 0 || end < start {
+		return fmt.Errorf("self assessment is not a JSON array: %v", response)
+	}
+	var answers []struct {
+		ID     string `json:"id"`
+		Met    bool   `json:"met"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &answers); err != nil {
+		return fmt.Errorf("parsing self assessment: %w", err)
+	}
+
+	byID := map[string]int{}
+	for _, i := range indexes {
+		byID[results[i].ID] = i
+		results[i].Detail = "not assessed"
+	}
+	for _, a := range answers {
+		if i, ok := byID[a.ID]; ok {
+			results[i].Met = a.Met
+			results[i].Detail = a.Reason
+		}
+	}
+	return nil
+}
+
+// Report formats results as a met/unmet checklist.
+func Report(results []Result) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	met := 0
+	for _, r := range results {
+		status := "UNMET"
+		if r.Met {
+			status = "met"
+			met++
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v", r.ID, status, r.Method, r.Text)
+		if r.Detail != "" {
+			fmt.Fprintf(w, "\t%v", r.Detail)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	fmt.Fprintf(&b, "%d/%d requirement(s) met\n", met, len(results))
+	return b.String()
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, " | ")
+}
@@ -0,0 +1,154 @@
+// Package spec describes a program as a structured requirements file and
+// checks a generated program against it requirement by requirement.
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jeremycruzz/msds301-wk9/pkg/session"
+)
+
+// Spec is a structured program description, loaded from JSON.
+type Spec struct {
+	Name         string        `json:"name"`
+	Description  string        `json:"description"`
+	Requirements []Requirement `json:"requirements"`
+	Inputs       []string      `json:"inputs,omitempty"`
+	Outputs      []string      `json:"outputs,omitempty"`
+	Examples     []Example     `json:"examples,omitempty"`
+	Constraints  []string      `json:"constraints,omitempty"`
+	// Tests are _test.go files, relative to the spec, copied into the
+	// project before requirements are checked.
+	Tests []string `json:"tests,omitempty"`
+	// Env is the environment tests, examples and sessions run the program
+	// with; nil inherits makego's.
+	Env []string `json:"-"`
+
+	dir string
+}
+
+// Requirement is one thing the program must do. It is proven by a go test,
+// by an example session, by a session transcript relative to the spec or,
+// when none is given, by asking chatgpt.
+type Requirement struct {
+	ID      string `json:"id"`
+	Text    string `json:"text"`
+	Test    string `json:"test,omitempty"`
+	Example string `json:"example,omitempty"`
+	Session string `json:"session,omitempty"`
+}
+
+// Method is how the requirement is checked: test, example, session or
+// model.
+func (r Requirement) Method() string {
+	switch {
+	case r.Test != "":
+		return "test"
+	case r.Example != "":
+		return "example"
+	case r.Session != "":
+		return "session"
+	}
+	return "model"
+}
+
+// Example is a golden session: the program is run with Args and Input on
+// stdin and every Output line must appear, in order, in what it prints.
+type Example struct {
+	Name   string   `json:"name"`
+	Args   []string `json:"args,omitempty"`
+	Input  string   `json:"input"`
+	Output []string `json:"output"`
+}
+
+// Load reads and validates a spec file.
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Spec
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing %v: %w", path, err)
+	}
+	s.dir = filepath.Dir(path)
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return &s, nil
+}
+
+func (s *Spec) validate() error {
+	if s.Description == "" {
+		return fmt.Errorf("description is required")
+	}
+	examples := map[string]bool{}
+	for _, e := range s.Examples {
+		examples[e.Name] = true
+	}
+	ids := map[string]bool{}
+	for i, r := range s.Requirements {
+		if r.ID == "" {
+			return fmt.Errorf("requirement %d has no id", i+1)
+		}
+		if ids[r.ID] {
+			return fmt.Errorf("duplicate requirement id %v", r.ID)
+		}
+		ids[r.ID] = true
+		if r.Example != "" && !examples[r.Example] {
+			return fmt.Errorf("requirement %v refers to unknown example %v", r.ID, r.Example)
+		}
+		if r.Session != "" {
+			if _, err := session.Load(filepath.Join(s.dir, r.Session)); err != nil {
+				return fmt.Errorf("requirement %v: %w", r.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Prompt renders the spec as the program description sent to chatgpt.
+func (s *Spec) Prompt() string {
+	var b strings.Builder
+	b.WriteString(s.Description)
+	b.WriteString("\n\nThe program must meet every one of these requirements:\n")
+	for _, r := range s.Requirements {
+		fmt.Fprintf(&b, "- %v: %v\n", r.ID, r.Text)
+	}
+	list(&b, "Inputs", s.Inputs)
+	list(&b, "Outputs", s.Outputs)
+	list(&b, "Constraints", s.Constraints)
+	if len(s.Examples) > 0 {
+		b.WriteString("\nExample sessions (stdin followed by lines the output must contain):\n")
+		for _, e := range s.Examples {
+			fmt.Fprintf(&b, "%v:\n", e.Name)
+			if len(e.Args) > 0 {
+				fmt.Fprintf(&b, "  arguments: %v\n", strings.Join(e.Args, " "))
+			}
+			fmt.Fprintf(&b, "  input:\n%v\n  output:\n%v\n", indent(e.Input), indent(strings.Join(e.Output, "\n")))
+		}
+	}
+	return b.String()
+}
+
+func list(b *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n%v:\n", title)
+	for _, item := range items {
+		fmt.Fprintf(b, "- %v\n", item)
+	}
+}
+
+func indent(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = "    " + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,76 @@
+package spec
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{"valid", `{"description": "d", "requirements": [{"id": "R1", "example": "e"}, {"id": "R2", "session": "s.txt"}], "examples": [{"name": "e"}]}`, ""},
+		{"bad json", `{"description": `, "parsing "},
+		{"no description", `{"requirements": [{"id": "R1"}]}`, "description is required"},
+		{"no id", `{"description": "d", "requirements": [{"id": "R1"}, {"text": "t"}]}`, "requirement 2 has no id"},
+		{"duplicate id", `{"description": "d", "requirements": [{"id": "R1"}, {"id": "R1"}]}`, "duplicate requirement id R1"},
+		{"unknown example", `{"description": "d", "requirements": [{"id": "R1", "example": "e"}]}`, "requirement R1 refers to unknown example e"},
+		{"missing session", `{"description": "d", "requirements": [{"id": "R1", "session": "missing.txt"}]}`, "requirement R1: "},
+		{"bad session", `{"description": "d", "requirements": [{"id": "R1", "session": "bad.txt"}]}`, `unknown directive "wait"`},
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{"s.txt": "expect hi\nexit 0\n", "bad.txt": "wait 5\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "spec.json")
+			if err := os.WriteFile(path, []byte(tt.spec), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Load() = %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Load() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMethod(t *testing.T) {
+	tests := []struct {
+		req  Requirement
+		want string
+	}{
+		{Requirement{ID: "R1", Test: "TestX", Example: "e"}, "test"},
+		{Requirement{ID: "R2", Example: "e", Session: "s.txt"}, "example"},
+		{Requirement{ID: "R3", Session: "s.txt"}, "session"},
+		{Requirement{ID: "R4", Text: "t"}, "model"},
+	}
+	for _, tt := range tests {
+		if got := tt.req.Method(); got != tt.want {
+			t.Errorf("%v.Method() = %v, want %v", tt.req.ID, got, tt.want)
+		}
+	}
+}
+
+func TestLoadRepoSpecs(t *testing.T) {
+	s, err := Load("../../specs/blackjack.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompt := s.Prompt()
+	for _, r := range s.Requirements {
+		if !strings.Contains(prompt, "- "+r.ID+": ") {
+			t.Errorf("prompt does not list requirement %v", r.ID)
+		}
+	}
+}